@@ -1,24 +1,33 @@
 package dev
 
 import (
-	"bytes"
 	"context"
 	goerrors "errors"
 	"fmt"
-	"io"
-	"net/http"
+	"io/fs"
 	"path"
+	"strings"
+	"sync"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+var crdGroupKind = schema.GroupKind{
+	Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition",
+}
+
 var defaultSchemeBuilder runtime.SchemeBuilder = runtime.SchemeBuilder{
 	clientgoscheme.AddToScheme,
 	apiextensionsv1.AddToScheme,
@@ -28,14 +37,28 @@ type ClusterConfig struct {
 	SchemeBuilder runtime.SchemeBuilder
 	NewWaiter     NewWaiterFunc
 	WaitOptions   []WaitOption
-	NewHelm       NewHelmFunc
-	HelmOptions   []HelmOption
-	NewRestConfig NewRestConfigFunc
-	NewCtrlClient NewCtrlClientFunc
+	// Readiness checks passed to the Waiter in addition to WaitOptions.
+	ReadinessChecks ReadinessChecks
+	NewHelm         NewHelmFunc
+	HelmOptions     []HelmOption
+	NewRestConfig   NewRestConfigFunc
+	NewCtrlClient   NewCtrlClientFunc
+	// Options for the HttpCache of the cluster, caching into WorkDir.
+	HttpCacheOptions []HttpCacheOption
+	// Download manifests of CreateAndWaitFromHttp through the HttpCache,
+	// instead of downloading them on every call without retries.
+	CacheHttpDownloads bool
 
 	WorkDir string
 	// Path to the kubeconfig of the cluster
 	Kubeconfig string
+
+	// Persists the inventory of created objects into this ConfigMap, when set.
+	// Takes precedence over InventoryFile.
+	InventoryConfigMap *client.ObjectKey
+	// Persists the inventory of created objects into this file, when set.
+	// Relative paths are relative to WorkDir.
+	InventoryFile string
 }
 
 type NewWaiterFunc func(
@@ -56,7 +79,13 @@ func DefaultNewRestConfig(kubeconfig string) (*rest.Config, error) {
 
 type NewCtrlClientFunc func(c *rest.Config, opts client.Options) (client.Client, error)
 
-var DefaultNewCtrlClientFunc = client.New
+// Creates a client that is also able to watch objects,
+// so it can be used with the WithWatch WaitOption.
+var DefaultNewCtrlClientFunc NewCtrlClientFunc = func(
+	c *rest.Config, opts client.Options,
+) (client.Client, error) {
+	return client.NewWithWatch(c, opts)
+}
 
 func (c *ClusterConfig) Default() {
 	if c.NewWaiter == nil {
@@ -80,6 +109,65 @@ type ClusterOption interface {
 	ApplyToClusterConfig(c *ClusterConfig)
 }
 
+const (
+	// Field manager used for server-side apply, when none is configured.
+	DefaultFieldManager = "devkube"
+	// Number of objects created concurrently within an install wave, when none is configured.
+	CreateDefaultWorkers = 10
+)
+
+// Configures how objects are created by the CreateAndWaitFrom* methods.
+type CreateConfig struct {
+	WaitOptions []WaitOption
+	// Options for loading objects in the CreateAndWaitFrom* methods.
+	LoadOptions []LoadOption
+	// Transform the objects of all sources before they are created.
+	Transformers []Transformer
+	// Create all objects first and wait for their readiness concurrently afterwards.
+	// CustomResourceDefinitions are still waited on right after they are created,
+	// so custom resources following them can be created.
+	ConcurrentWaits bool
+	// Sort objects into install waves before creating them,
+	// see SortObjectsByInstallWave.
+	InstallOrder bool
+	// Create objects in install waves, see GroupObjectsByInstallWave.
+	// Objects of the same wave are created and waited on concurrently,
+	// a wave is only started when all objects of the previous wave are ready.
+	// Takes precedence over ConcurrentWaits and InstallOrder.
+	InstallWaves bool
+	// Number of objects created and waited on concurrently within an install wave.
+	// Defaults to CreateDefaultWorkers.
+	Workers int
+	// Only server-side dry-run the objects and record
+	// the differences to the live objects in this report, when set.
+	// Nothing is created or waited on, see Cluster.Diff.
+	Diff *DiffReport
+	// Server-side apply objects instead of creating them,
+	// so changes to existing objects are applied too.
+	ServerSideApply *ServerSideApplyConfig
+}
+
+// Configures server-side apply.
+type ServerSideApplyConfig struct {
+	// Defaults to DefaultFieldManager.
+	FieldManager string
+	// Take ownership of fields owned by other field managers instead of failing.
+	ForceConflicts bool
+}
+
+func (c *CreateConfig) Default() {
+	if c.Workers <= 0 {
+		c.Workers = CreateDefaultWorkers
+	}
+	if c.ServerSideApply != nil && len(c.ServerSideApply.FieldManager) == 0 {
+		c.ServerSideApply.FieldManager = DefaultFieldManager
+	}
+}
+
+type CreateOption interface {
+	ApplyToCreateConfig(c *CreateConfig)
+}
+
 // Container object to hold kubernetes client interfaces and configuration.
 type Cluster struct {
 	Scheme     *runtime.Scheme
@@ -87,8 +175,16 @@ type Cluster struct {
 	CtrlClient client.Client
 	Waiter     *Waiter
 	Helm       *Helm
+	// Caches manifests downloaded by CreateAndWaitFromHttp, when CacheHttpDownloads is set.
+	// Use HttpCache.Source to load pinned resources.
+	HttpCache *HttpCache
+	// Objects created through this Cluster.
+	Inventory *Inventory
 
 	config ClusterConfig
+	// Lazily created from RestConfig, see discoveryClient.
+	discovery    discovery.DiscoveryInterface
+	discoveryMux sync.Mutex
 }
 
 // Creates a new Cluster object to interact with a Kubernetes cluster.
@@ -133,12 +229,36 @@ func NewCluster(workDir string, opts ...ClusterOption) (*Cluster, error) {
 		return nil, fmt.Errorf("creating new ctrl client: %w", err)
 	}
 
+	waitOpts := c.config.WaitOptions
+	if len(c.config.ReadinessChecks) > 0 {
+		waitOpts = append([]WaitOption{
+			WithReadinessChecks(c.config.ReadinessChecks),
+		}, waitOpts...)
+	}
 	c.Waiter = c.config.NewWaiter(
 		c.CtrlClient, c.Scheme,
-		c.config.WaitOptions...)
+		waitOpts...)
 	c.Helm = c.config.NewHelm(
 		workDir, c.config.Kubeconfig,
 		c.config.HelmOptions...)
+	c.HttpCache = NewHttpCache(
+		path.Join(workDir, "http-cache"),
+		c.config.HttpCacheOptions...)
+
+	var inventoryStore inventoryStore
+	switch {
+	case c.config.InventoryConfigMap != nil:
+		inventoryStore = &configMapInventoryStore{
+			client: c.CtrlClient, key: *c.config.InventoryConfigMap,
+		}
+	case len(c.config.InventoryFile) > 0:
+		inventoryFile := c.config.InventoryFile
+		if !path.IsAbs(inventoryFile) {
+			inventoryFile = path.Join(workDir, inventoryFile)
+		}
+		inventoryStore = &fileInventoryStore{path: inventoryFile}
+	}
+	c.Inventory = newInventory(inventoryStore)
 
 	return c, nil
 }
@@ -148,96 +268,496 @@ func (c *Cluster) Kubeconfig() string {
 	return c.config.Kubeconfig
 }
 
+// Loads objects from the given sources in order,
+// creates these objects and waits for them to be ready.
+func (c *Cluster) CreateAndWait(
+	ctx context.Context, sources ...ObjectSource,
+) error {
+	return c.CreateAndWaitWithOptions(ctx, sources)
+}
+
+// Loads objects from the given sources in order,
+// creates these objects with the given options and waits for them to be ready.
+func (c *Cluster) CreateAndWaitWithOptions(
+	ctx context.Context, sources []ObjectSource,
+	opts ...CreateOption,
+) error {
+	// Source locations are reported in errors and stripped before sending objects.
+	config := CreateConfig{LoadOptions: []LoadOption{WithSourceLocations(true)}}
+	for _, opt := range opts {
+		opt.ApplyToCreateConfig(&config)
+	}
+
+	source := ObjectSources(sources)
+	objects, err := source.LoadObjects(ctx, config.LoadOptions...)
+	if err != nil {
+		return err
+	}
+	objects, err = Transformers(config.Transformers).Transform(objects)
+	if err != nil {
+		return fmt.Errorf("transforming objects from %s: %w", source, err)
+	}
+	return c.createObjectsFromSource(ctx, source.String(), objects, opts...)
+}
+
 // Load kube objects from a list of http urls,
 // create these objects and wait for them to be ready.
+// Downloads go through the HttpCache when enabled with WithCacheHttpDownloads.
+// CreateOptions that are also WaitOptions, like WithConcurrentWaits, are passed on as such.
 func (c *Cluster) CreateAndWaitFromHttp(
 	ctx context.Context, urls []string,
 	opts ...WaitOption,
 ) error {
-	var client http.Client
-	var objects []unstructured.Unstructured
-	for _, url := range urls {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return fmt.Errorf("creating request: %w", err)
-		}
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return fmt.Errorf("getting %q: %w", url, err)
-		}
-		defer resp.Body.Close()
-
-		var content bytes.Buffer
-		if _, err := io.Copy(&content, resp.Body); err != nil {
-			return fmt.Errorf("reading response %q: %w", url, err)
-		}
-
-		objs, err := LoadKubernetesObjectsFromBytes(content.Bytes())
-		if err != nil {
-			return fmt.Errorf("loading objects from %q: %w", url, err)
-		}
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{c.httpSource(urls)}, createOptionsFromWaitOptions(opts)...)
+}
 
-		objects = append(objects, objs...)
+// Returns a source loading objects from the given urls,
+// through the HttpCache if CacheHttpDownloads is set.
+func (c *Cluster) httpSource(urls []string) ObjectSource {
+	if !c.config.CacheHttpDownloads || c.HttpCache == nil {
+		return HttpSource(urls)
 	}
-
-	return c.createObjectsFromSource(ctx, "http", objects)
+	resources := make([]HttpResource, len(urls))
+	for i, url := range urls {
+		resources[i] = HttpResource{URL: url}
+	}
+	return c.HttpCache.Source(resources...)
 }
 
 // Load kube objects from a list of files,
 // create these objects and wait for them to be ready.
+// CreateOptions that are also WaitOptions, like WithConcurrentWaits, are passed on as such.
 func (c *Cluster) CreateAndWaitFromFiles(
 	ctx context.Context, files []string,
 	opts ...WaitOption,
 ) error {
-	var objects []unstructured.Unstructured
-	for _, file := range files {
-		objs, err := LoadKubernetesObjectsFromFile(file)
-		if err != nil {
-			return fmt.Errorf("loading objects from file %q: %w", file, err)
-		}
-
-		objects = append(objects, objs...)
-	}
-
-	return c.createObjectsFromSource(ctx, "files", objects)
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{FilesSource(files)}, createOptionsFromWaitOptions(opts)...)
 }
 
 // Load kube objects from a list of folders,
 // create these objects and wait for them to be ready.
+// CreateOptions that are also WaitOptions, like WithConcurrentWaits, are passed on as such.
 func (c *Cluster) CreateAndWaitFromFolders(
 	ctx context.Context, folders []string,
 	opts ...WaitOption,
 ) error {
-	var objects []unstructured.Unstructured
-	for _, folder := range folders {
-		objs, err := LoadKubernetesObjectsFromFolder(folder)
-		if err != nil {
-			return fmt.Errorf("loading objects from folder %q: %w", folder, err)
-		}
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{FoldersSource(folders)}, createOptionsFromWaitOptions(opts)...)
+}
 
-		objects = append(objects, objs...)
+// Passes on options that are also CreateOptions as such,
+// so create-only options can be used by the methods accepting WaitOptions.
+func createOptionsFromWaitOptions(opts []WaitOption) []CreateOption {
+	createOpts := make([]CreateOption, len(opts))
+	for i, opt := range opts {
+		if createOpt, ok := opt.(CreateOption); ok {
+			createOpts[i] = createOpt
+			continue
+		}
+		createOpts[i] = WithWaitOptions{opt}
 	}
+	return createOpts
+}
+
+// Load kube objects from a list of files within fsys,
+// create these objects and wait for them to be ready.
+func (c *Cluster) CreateAndWaitFromFSFiles(
+	ctx context.Context, fsys fs.FS, files []string,
+	opts ...CreateOption,
+) error {
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{FSSource{FS: fsys, Files: files}}, opts...)
+}
+
+// Load kube objects from a list of folders within fsys,
+// create these objects and wait for them to be ready.
+func (c *Cluster) CreateAndWaitFromFSFolders(
+	ctx context.Context, fsys fs.FS, folders []string,
+	opts ...CreateOption,
+) error {
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{FSSource{FS: fsys, Folders: folders}}, opts...)
+}
 
-	return c.createObjectsFromSource(ctx, "folders", objects)
+// Build kube objects from a list of kustomization directories,
+// create these objects and wait for them to be ready.
+func (c *Cluster) CreateAndWaitFromKustomizations(
+	ctx context.Context, dirs []string,
+	opts ...CreateOption,
+) error {
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{KustomizationsSource(dirs)}, opts...)
+}
+
+// Build kube objects from a list of kustomization directories within fsys,
+// create these objects and wait for them to be ready.
+func (c *Cluster) CreateAndWaitFromFSKustomizations(
+	ctx context.Context, fsys fs.FS, dirs []string,
+	opts ...CreateOption,
+) error {
+	return c.CreateAndWaitWithOptions(ctx,
+		[]ObjectSource{FSSource{FS: fsys, Kustomizations: dirs}}, opts...)
 }
 
-func (c *Cluster) createObjectsFromSource(ctx context.Context, source string, objects []unstructured.Unstructured, opts ...WaitOption) error {
-	for i := range objects {
-		if err := c.CreateAndWaitForReadiness(ctx, &objects[i], opts...); err != nil {
+// Creates or applies the objects and waits for them to be ready.
+// Custom resources defined by CRDs within objects are only created
+// when their CRD is established and the kind is discoverable.
+// Apply conflicts don't stop the remaining objects from being applied,
+// they are reported together at the end instead.
+func (c *Cluster) createObjectsFromSource(
+	ctx context.Context, source string,
+	objects []unstructured.Unstructured, opts ...CreateOption,
+) (err error) {
+	var config CreateConfig
+	for _, opt := range opts {
+		opt.ApplyToCreateConfig(&config)
+	}
+	config.Default()
+
+	if config.Diff != nil {
+		diffs, err := c.Diff(ctx, objects, opts...)
+		config.Diff.add(diffs...)
+		if err != nil {
+			return fmt.Errorf("diffing from %s: %w", source, err)
+		}
+		return nil
+	}
+	defer c.saveInventory(ctx, &err)
+
+	crdGKs := crdGroupKinds(objects)
+	var conflictErrs []error
+	if config.InstallWaves {
+		var err error
+		conflictErrs, err = c.createObjectsInWaves(ctx, objects, config, crdGKs)
+		if err != nil {
 			return fmt.Errorf("creating from %s: %w", source, err)
 		}
+	} else {
+		if config.InstallOrder {
+			SortObjectsByInstallWave(objects)
+		}
+
+		var created []client.Object
+		for i := range objects {
+			obj := &objects[i]
+			err := c.createOrApplyObject(ctx, obj, config, crdGKs)
+			if isApplyConflictError(err) {
+				conflictErrs = append(conflictErrs, err)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("creating from %s: %w", source, err)
+			}
+
+			// Custom resources following the CRD can only be created when it's established.
+			if config.ConcurrentWaits && obj.GroupVersionKind().GroupKind() != crdGroupKind {
+				created = append(created, obj)
+				continue
+			}
+			if err := c.waitForReadiness(ctx, obj, config.WaitOptions...); err != nil {
+				return fmt.Errorf("creating from %s: %w", source, err)
+			}
+		}
+
+		if err := c.Waiter.WaitForAllReadiness(ctx, created, config.WaitOptions...); err != nil {
+			return fmt.Errorf("waiting for objects from %s: %w", source, err)
+		}
+	}
+
+	if len(conflictErrs) > 0 {
+		return fmt.Errorf("applying from %s: %w", source, goerrors.Join(conflictErrs...))
 	}
 	return nil
 }
 
+// Creates the objects wave by wave, see GroupObjectsByInstallWave.
+// Objects of the same wave are created and waited on concurrently by config.Workers workers.
+// A wave is only started when all objects of the previous wave are ready.
+func (c *Cluster) createObjectsInWaves(
+	ctx context.Context, objects []unstructured.Unstructured,
+	config CreateConfig, crdGKs map[schema.GroupKind]string,
+) (conflictErrs []error, err error) {
+	for _, wave := range GroupObjectsByInstallWave(objects) {
+		var (
+			wg   sync.WaitGroup
+			sem  = make(chan struct{}, config.Workers)
+			errs = make([]error, len(wave))
+		)
+		for i := range wave {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, obj *unstructured.Unstructured) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				if err := c.createOrApplyObject(ctx, obj, config, crdGKs); err != nil {
+					errs[i] = err
+					return
+				}
+				if err := c.waitForReadiness(ctx, obj, config.WaitOptions...); err != nil {
+					errs[i] = fmt.Errorf("%s %s: %w",
+						obj.GroupVersionKind(), client.ObjectKeyFromObject(obj), err)
+				}
+			}(i, &wave[i])
+		}
+		wg.Wait()
+
+		var waveErrs []error
+		for _, err := range errs {
+			if isApplyConflictError(err) {
+				conflictErrs = append(conflictErrs, err)
+			} else if err != nil {
+				waveErrs = append(waveErrs, err)
+			}
+		}
+		if len(waveErrs) > 0 {
+			return conflictErrs, goerrors.Join(waveErrs...)
+		}
+	}
+	return conflictErrs, nil
+}
+
+// Creates or applies the object, see createOrApply.
+// Custom resources of the given CRD GroupKinds are only created when their kind is discoverable.
+func (c *Cluster) createOrApplyObject(
+	ctx context.Context, obj *unstructured.Unstructured,
+	config CreateConfig, crdGKs map[schema.GroupKind]string,
+) error {
+	if _, ok := crdGKs[obj.GroupVersionKind().GroupKind()]; ok {
+		if err := c.Waiter.WaitForKindDiscoverable(
+			ctx, obj.GroupVersionKind(), config.WaitOptions...); err != nil {
+			return wrapWithSourceLocation(obj, err)
+		}
+	}
+	return wrapWithSourceLocation(obj, c.createOrApply(ctx, obj, config))
+}
+
 // Creates the given objects and waits for them to be considered ready.
 func (c *Cluster) CreateAndWaitForReadiness(
 	ctx context.Context, object client.Object,
 	opts ...WaitOption,
+) (err error) {
+	defer c.saveInventory(ctx, &err)
+	if err := c.create(ctx, object); err != nil {
+		return wrapWithSourceLocation(object, err)
+	}
+	return c.waitForReadiness(ctx, object, opts...)
+}
+
+// Waits for the object to be considered ready,
+// ignoring types without a registered readiness check.
+func (c *Cluster) waitForReadiness(
+	ctx context.Context, object client.Object,
+	opts ...WaitOption,
+) error {
+	// Waiting overwrites the object with the cluster copy, which lacks the SourceAnnotation.
+	return keepSourceLocation(object, func() error {
+		if err := c.Waiter.WaitForReadiness(ctx, object, opts...); err != nil {
+			var unknownTypeErr *UnknownTypeError
+			if goerrors.As(err, &unknownTypeErr) {
+				// A lot of types don't require waiting for readiness,
+				// so we should not error in cases when object types
+				// are not registered for the generic wait method.
+				return nil
+			}
+
+			return fmt.Errorf("waiting for object: %w", err)
+		}
+		return nil
+	})
+}
+
+// Deletes the given object with the given propagation policy and waits for it to be gone.
+// An empty propagation policy uses the default policy of the object kind.
+// When the object does not disappear in time, the returned *TimeoutError names
+// the finalizers still present and, for foreground deletion, the dependents blocking it.
+func (c *Cluster) DeleteAndWait(
+	ctx context.Context, object client.Object,
+	propagation metav1.DeletionPropagation, opts ...WaitOption,
+) error {
+	if err := c.delete(ctx, object, propagation); err != nil {
+		return err
+	}
+	return c.waitToBeGone(ctx, object, opts...)
+}
+
+// Deletes the given object, ignoring if it does not exist.
+func (c *Cluster) delete(
+	ctx context.Context, object client.Object,
+	propagation metav1.DeletionPropagation,
+) error {
+	var deleteOpts []client.DeleteOption
+	if len(propagation) > 0 {
+		deleteOpts = append(deleteOpts, client.PropagationPolicy(propagation))
+	}
+	if err := c.CtrlClient.Delete(ctx, object, deleteOpts...); err != nil &&
+		!errors.IsNotFound(err) {
+		gvk := object.GetObjectKind().GroupVersionKind()
+		return fmt.Errorf("deleting object: %s %s: %w",
+			gvk, client.ObjectKeyFromObject(object), err)
+	}
+	return nil
+}
+
+// Waits for the given object to be gone.
+// Adds the dependents blocking foreground deletion to the *TimeoutError.
+func (c *Cluster) waitToBeGone(
+	ctx context.Context, object client.Object, opts ...WaitOption,
+) error {
+	err := c.Waiter.WaitToBeGone(ctx, object, nil, opts...)
+	var timeoutErr *TimeoutError
+	if !goerrors.As(err, &timeoutErr) ||
+		!controllerutil.ContainsFinalizer(object, metav1.FinalizerDeleteDependents) {
+		return err
+	}
+
+	// The original context has most likely expired.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+	disco, dErr := c.discoveryClient()
+	if dErr == nil {
+		timeoutErr.BlockingDependents, dErr = objectBlockingDependents(
+			ctx, c.CtrlClient, disco, object)
+	}
+	if dErr != nil {
+		timeoutErr.DiagnosticsErr = goerrors.Join(timeoutErr.DiagnosticsErr,
+			fmt.Errorf("looking up blocking dependents: %w", dErr))
+	}
+	return err
+}
+
+// Returns the discovery client, creating it on first use.
+// Safe for concurrent use, e.g. by concurrent DeleteAndWait calls.
+func (c *Cluster) discoveryClient() (discovery.DiscoveryInterface, error) {
+	c.discoveryMux.Lock()
+	defer c.discoveryMux.Unlock()
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+	if c.RestConfig == nil {
+		return nil, goerrors.New("no rest config")
+	}
+	disco, err := discovery.NewDiscoveryClientForConfig(c.RestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery client: %w", err)
+	}
+	c.discovery = disco
+	return disco, nil
+}
+
+// ApplyConflictError is returned when server-side applying an object
+// conflicts with fields owned by other field managers.
+type ApplyConflictError struct {
+	GVK schema.GroupVersionKind
+	Key client.ObjectKey
+	// Conflicting fields as reported by the API server.
+	Conflicts []metav1.StatusCause
+
+	err error
+}
+
+func (e *ApplyConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "applying %s %s: field manager conflict", e.GVK, e.Key)
+	for _, cause := range e.Conflicts {
+		fmt.Fprintf(&b, "\n  - %s", cause.Message)
+	}
+	return b.String()
+}
+
+func (e *ApplyConflictError) Unwrap() error {
+	return e.err
+}
+
+func isApplyConflictError(err error) bool {
+	var conflictErr *ApplyConflictError
+	return goerrors.As(err, &conflictErr)
+}
+
+// Creates or server-side applies the given object, depending on the config.
+func (c *Cluster) createOrApply(
+	ctx context.Context, object client.Object, config CreateConfig,
+) error {
+	if config.ServerSideApply == nil {
+		return c.create(ctx, object)
+	}
+	return withoutSourceLocation(object, func() error {
+		return c.apply(ctx, object, *config.ServerSideApply)
+	})
+}
+
+// Server-side applies the given object and updates it with the response.
+// Objects that did not exist before are recorded in the inventory.
+func (c *Cluster) apply(
+	ctx context.Context, object client.Object, config ServerSideApplyConfig,
 ) error {
-	if err := c.CtrlClient.Create(ctx, object); err != nil &&
-		!errors.IsAlreadyExists(err) {
+	gvk, err := apiutil.GVKForObject(object, c.Scheme)
+	if err != nil {
+		return err
+	}
+
+	// Apply patches have to carry apiVersion and kind and must not carry managed fields.
+	obj := &unstructured.Unstructured{}
+	if err := copyToUnstructured(object, obj); err != nil {
+		return err
+	}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+
+	// Only objects created by the apply are recorded in the inventory,
+	// so Teardown does not delete objects that existed before.
+	existing := &metav1.PartialObjectMetadata{}
+	existing.SetGroupVersionKind(gvk)
+	err = c.CtrlClient.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting object: %s %s: %w",
+			gvk, client.ObjectKeyFromObject(object), err)
+	}
+	created := errors.IsNotFound(err)
+
+	patchOpts := []client.PatchOption{client.FieldOwner(config.FieldManager)}
+	if config.ForceConflicts {
+		patchOpts = append(patchOpts, client.ForceOwnership)
+	}
+	err = c.CtrlClient.Patch(ctx, obj, client.Apply, patchOpts...)
+	if errors.IsConflict(err) {
+		conflictErr := &ApplyConflictError{
+			GVK: gvk,
+			Key: client.ObjectKeyFromObject(object),
+			err: err,
+		}
+		var statusErr errors.APIStatus
+		if goerrors.As(err, &statusErr) && statusErr.Status().Details != nil {
+			conflictErr.Conflicts = statusErr.Status().Details.Causes
+		}
+		return conflictErr
+	}
+	if err != nil {
+		return fmt.Errorf("applying object: %s %s: %w",
+			gvk, client.ObjectKeyFromObject(object), err)
+	}
+	if created {
+		if err := c.recordInInventory(ctx, object); err != nil {
+			return err
+		}
+	}
+	return copyFromUnstructured(obj, object)
+}
+
+// Creates the given object, ignoring if it already exists.
+// Objects that have been created are recorded in the inventory.
+// The SourceAnnotation is not sent to the cluster.
+func (c *Cluster) create(ctx context.Context, object client.Object) error {
+	err := withoutSourceLocation(object, func() error {
+		return c.CtrlClient.Create(ctx, object)
+	})
+	if err == nil {
+		return c.recordInInventory(ctx, object)
+	}
+	if !errors.IsAlreadyExists(err) {
 		gvk := object.GetObjectKind().GroupVersionKind()
 		return fmt.Errorf("creating object: %s/%s/%s %s/%s: %w",
 			gvk.Group,
@@ -245,17 +765,5 @@ func (c *Cluster) CreateAndWaitForReadiness(
 			gvk.Kind,
 			object.GetNamespace(), object.GetName(), err)
 	}
-
-	if err := c.Waiter.WaitForReadiness(ctx, object, opts...); err != nil {
-		var unknownTypeErr *UnknownTypeError
-		if goerrors.As(err, &unknownTypeErr) {
-			// A lot of types don't require waiting for readiness,
-			// so we should not error in cases when object types
-			// are not registered for the generic wait method.
-			return nil
-		}
-
-		return fmt.Errorf("waiting for object: %w", err)
-	}
 	return nil
 }
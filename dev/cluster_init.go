@@ -3,6 +3,7 @@ package dev
 import (
 	"context"
 	"fmt"
+	"io/fs"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -25,6 +26,13 @@ func (l ClusterLoadObjectsFromFolders) Init(
 	return cluster.CreateAndWaitFromFolders(ctx, l)
 }
 
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromFolders) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitWithOptions(ctx, []ObjectSource{FoldersSource(l)}, opts...)
+	}
+}
+
 // Load objects from given file paths and applies them into the cluster.
 type ClusterLoadObjectsFromFiles []string
 
@@ -33,6 +41,13 @@ func (l ClusterLoadObjectsFromFiles) Init(
 	return cluster.CreateAndWaitFromFiles(ctx, l)
 }
 
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromFiles) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitWithOptions(ctx, []ObjectSource{FilesSource(l)}, opts...)
+	}
+}
+
 // Load objects from the given http urls and applies them into the cluster.
 type ClusterLoadObjectsFromHttp []string
 
@@ -41,6 +56,94 @@ func (l ClusterLoadObjectsFromHttp) Init(
 	return cluster.CreateAndWaitFromHttp(ctx, l)
 }
 
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromHttp) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitWithOptions(ctx,
+			[]ObjectSource{cluster.httpSource(l)}, opts...)
+	}
+}
+
+// Builds objects from the given kustomization directories and applies them into the cluster.
+type ClusterLoadObjectsFromKustomization []string
+
+func (l ClusterLoadObjectsFromKustomization) Init(
+	ctx context.Context, cluster *Cluster) error {
+	return cluster.CreateAndWaitFromKustomizations(ctx, l)
+}
+
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromKustomization) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitFromKustomizations(ctx, l, opts...)
+	}
+}
+
+// Load objects from the given folder paths within FS and applies them into the cluster.
+type ClusterLoadObjectsFromFSFolders struct {
+	FS      fs.FS
+	Folders []string
+}
+
+func (l ClusterLoadObjectsFromFSFolders) Init(
+	ctx context.Context, cluster *Cluster) error {
+	return cluster.CreateAndWaitFromFSFolders(ctx, l.FS, l.Folders)
+}
+
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromFSFolders) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitFromFSFolders(ctx, l.FS, l.Folders, opts...)
+	}
+}
+
+// Load objects from the given file paths within FS and applies them into the cluster.
+type ClusterLoadObjectsFromFSFiles struct {
+	FS    fs.FS
+	Files []string
+}
+
+func (l ClusterLoadObjectsFromFSFiles) Init(
+	ctx context.Context, cluster *Cluster) error {
+	return cluster.CreateAndWaitFromFSFiles(ctx, l.FS, l.Files)
+}
+
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromFSFiles) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitFromFSFiles(ctx, l.FS, l.Files, opts...)
+	}
+}
+
+// Builds objects from the given kustomization directories within FS and applies them into the cluster.
+type ClusterLoadObjectsFromFSKustomization struct {
+	FS   fs.FS
+	Dirs []string
+}
+
+func (l ClusterLoadObjectsFromFSKustomization) Init(
+	ctx context.Context, cluster *Cluster) error {
+	return cluster.CreateAndWaitFromFSKustomizations(ctx, l.FS, l.Dirs)
+}
+
+// Returns an initializer creating the objects with the given options.
+func (l ClusterLoadObjectsFromFSKustomization) WithOptions(opts ...CreateOption) ClusterInitFn {
+	return func(ctx context.Context, cluster *Cluster) error {
+		return cluster.CreateAndWaitFromFSKustomizations(ctx, l.FS, l.Dirs, opts...)
+	}
+}
+
+// Loads objects from Source and applies them into the cluster with the given options.
+type ClusterLoadObjectsFromSource struct {
+	Source  ObjectSource
+	Options []CreateOption
+}
+
+func (l ClusterLoadObjectsFromSource) Init(
+	ctx context.Context, cluster *Cluster) error {
+	return cluster.CreateAndWaitWithOptions(ctx, []ObjectSource{l.Source}, l.Options...)
+}
+
 // Creates the referenced Object and waits for it to be ready.
 type ClusterLoadObjectFromClientObject struct {
 	client.Object
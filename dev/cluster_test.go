@@ -1,13 +1,30 @@
 package dev
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestClusterConfig_Default(t *testing.T) {
@@ -71,3 +88,414 @@ func (m *NewCtrlClientMock) New(
 	// TODO: come up with a smart way to return a nil client:
 	return nil, args.Error(1)
 }
+
+func newTestCluster(t *testing.T, objs ...client.Object) *Cluster {
+	t.Helper()
+	return newTestClusterWithInterceptor(t, interceptor.Funcs{}, objs...)
+}
+
+func newTestClusterWithInterceptor(
+	t *testing.T, funcs interceptor.Funcs, objs ...client.Object,
+) *Cluster {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, defaultSchemeBuilder.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithInterceptorFuncs(funcs).
+		// required to collect events for diagnostics.
+		WithIndex(&corev1.Event{}, "involvedObject.name", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Event).InvolvedObject.Name}
+		}).
+		WithIndex(&corev1.Event{}, "involvedObject.kind", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Event).InvolvedObject.Kind}
+		}).
+		Build()
+	return &Cluster{
+		Scheme:     scheme,
+		CtrlClient: c,
+		Waiter: NewWaiter(c, scheme,
+			WithInterval(10*time.Millisecond),
+			WithTimeout(time.Second)),
+		Inventory: newInventory(nil),
+	}
+}
+
+func TestCluster_createObjectsFromSource(t *testing.T) {
+	deployments, err := LoadKubernetesObjectsFromFile("testdata/deployment.yaml")
+	require.NoError(t, err)
+	objects := append([]unstructured.Unstructured{*newTestCR("")}, deployments...)
+
+	for _, concurrent := range []bool{false, true} {
+		t.Run(fmt.Sprintf("concurrent=%t", concurrent), func(t *testing.T) {
+			cluster := newTestCluster(t)
+			ctx := context.Background()
+
+			err := cluster.createObjectsFromSource(ctx, "test",
+				deepCopyObjects(objects),
+				WithConcurrentWaits(concurrent),
+				WithTimeout(50*time.Millisecond))
+			// the fake client does not run controllers,
+			// so the Deployment never becomes available.
+			var timeoutErr *TimeoutError
+			require.ErrorAs(t, err, &timeoutErr)
+			assert.Equal(t, "test-deployment", timeoutErr.Key.Name)
+
+			// all objects have been created nevertheless.
+			for _, obj := range objects {
+				assert.NoError(t, cluster.CtrlClient.Get(ctx,
+					client.ObjectKeyFromObject(&obj), obj.DeepCopy()))
+			}
+		})
+	}
+}
+
+func TestCluster_CreateAndWaitFromFiles(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+
+	// existing callers pass wait options.
+	waitOpts := []WaitOption{WithTimeout(50 * time.Millisecond)}
+	err := cluster.CreateAndWaitFromFiles(ctx, []string{"testdata/deployment.yaml"}, waitOpts...)
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "test-deployment", timeoutErr.Key.Name)
+
+	// create-only options are passed on.
+	cmFile := filepath.Join(t.TempDir(), "cm.yaml")
+	require.NoError(t, os.WriteFile(cmFile,
+		[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: after\n  namespace: test\n"), 0o644))
+	err = cluster.CreateAndWaitFromFiles(ctx, []string{"testdata/deployment.yaml", cmFile},
+		WithTimeout(50*time.Millisecond), WithConcurrentWaits(true))
+	require.ErrorAs(t, err, &timeoutErr)
+	// the ConfigMap is created before waiting on the Deployment.
+	require.NoError(t, cluster.CtrlClient.Get(ctx,
+		client.ObjectKey{Namespace: "test", Name: "after"}, &corev1.ConfigMap{}))
+}
+
+func TestCluster_createObjectsFromSource_ServerSideApply(t *testing.T) {
+	ctx := context.Background()
+	newConfigMap := func(name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetName(name)
+		obj.SetNamespace("test")
+		return obj
+	}
+
+	var applied []string
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Patch: func(
+			ctx context.Context, c client.WithWatch, obj client.Object,
+			patch client.Patch, opts ...client.PatchOption,
+		) error {
+			// the fake client does not support server-side apply.
+			assert.Equal(t, types.ApplyPatchType, patch.Type())
+			patchOpts := &client.PatchOptions{}
+			patchOpts.ApplyOptions(opts)
+			assert.Equal(t, "test-manager", patchOpts.FieldManager)
+			assert.True(t, *patchOpts.Force)
+
+			if obj.GetName() == "conflicting" {
+				return errors.NewApplyConflict([]metav1.StatusCause{
+					{
+						Type:    metav1.CauseTypeFieldManagerConflict,
+						Message: `conflict with "kubectl": .data.key`,
+						Field:   ".data.key",
+					},
+				}, "Apply failed with 1 conflict")
+			}
+			applied = append(applied, obj.GetName())
+			if err := c.Create(ctx, obj); !errors.IsAlreadyExists(err) {
+				return err
+			}
+			return nil
+		},
+	}, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "test"}})
+
+	err := cluster.createObjectsFromSource(ctx, "test", []unstructured.Unstructured{
+		newConfigMap("first"),
+		newConfigMap("conflicting"),
+		newConfigMap("existing"),
+		newConfigMap("last"),
+	}, WithServerSideApply{FieldManager: "test-manager", ForceConflicts: true})
+
+	var conflictErr *ApplyConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, client.ObjectKey{Name: "conflicting", Namespace: "test"}, conflictErr.Key)
+	if assert.Len(t, conflictErr.Conflicts, 1) {
+		assert.Equal(t, ".data.key", conflictErr.Conflicts[0].Field)
+	}
+	assert.Contains(t, err.Error(), `conflict with "kubectl": .data.key`)
+	// conflicts don't stop other objects from being applied.
+	assert.Equal(t, []string{"first", "existing", "last"}, applied)
+
+	// objects that existed before are not recorded.
+	entries, err := cluster.Inventory.Entries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []InventoryEntry{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "first"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "last"},
+	}, entries)
+}
+
+func TestCluster_CreateAndWaitFromFiles_ServerSideApply(t *testing.T) {
+	var applied []string
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Patch: func(
+			ctx context.Context, c client.WithWatch, obj client.Object,
+			patch client.Patch, opts ...client.PatchOption,
+		) error {
+			// the fake client does not support server-side apply.
+			assert.Equal(t, types.ApplyPatchType, patch.Type())
+			applied = append(applied, obj.GetName())
+			return c.Create(ctx, obj)
+		},
+	})
+
+	cmFile := filepath.Join(t.TempDir(), "cm.yaml")
+	require.NoError(t, os.WriteFile(cmFile,
+		[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: applied\n  namespace: test\n"), 0o644))
+	err := cluster.CreateAndWaitFromFiles(context.Background(), []string{cmFile},
+		WithServerSideApply{FieldManager: "test-manager"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"applied"}, applied)
+}
+
+func TestCluster_createObjectsFromSource_InstallOrder(t *testing.T) {
+	var created []string
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Create: func(
+			ctx context.Context, c client.WithWatch,
+			obj client.Object, opts ...client.CreateOption,
+		) error {
+			created = append(created, obj.GetName())
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+
+	objects := []unstructured.Unstructured{
+		newTestObject("v1", "ConfigMap", "cm"),
+		newTestObject("v1", "ServiceAccount", "sa"),
+		newTestObject("v1", "Namespace", "ns"),
+	}
+	for i := range objects[:2] {
+		objects[i].SetNamespace("ns")
+	}
+	require.NoError(t, cluster.createObjectsFromSource(
+		context.Background(), "test", objects, WithInstallOrder(true)))
+	assert.Equal(t, []string{"ns", "sa", "cm"}, created)
+}
+
+func TestCluster_createObjectsFromSource_InstallWaves(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		created           []string
+		inFlight, maxSeen int
+	)
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Create: func(
+			ctx context.Context, c client.WithWatch,
+			obj client.Object, opts ...client.CreateOption,
+		) error {
+			mu.Lock()
+			created = append(created, obj.GetName())
+			inFlight++
+			maxSeen = max(maxSeen, inFlight)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+
+	deployments, err := LoadKubernetesObjectsFromFile("testdata/deployment.yaml")
+	require.NoError(t, err)
+	objects := append([]unstructured.Unstructured{
+		*newTestCR(""),
+		newTestObject("v1", "ConfigMap", "cm-1"),
+		newTestObject("v1", "ConfigMap", "cm-2"),
+		newTestObject("v1", "ConfigMap", "cm-3"),
+		newTestObject("v1", "Namespace", "test"),
+	}, deployments...)
+	for i := 1; i < 4; i++ {
+		objects[i].SetNamespace("test")
+	}
+
+	err = cluster.createObjectsFromSource(context.Background(), "test", objects,
+		WithInstallWaves(true), WithWorkers(2),
+		WithTimeout(50*time.Millisecond))
+	// the fake client does not run controllers,
+	// so the Deployment never becomes available.
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "test-deployment", timeoutErr.Key.Name)
+
+	// the custom resource wave is never started.
+	assert.Equal(t, "test", created[0])
+	assert.ElementsMatch(t, []string{"cm-1", "cm-2", "cm-3"}, created[1:4])
+	assert.Equal(t, []string{"test-deployment"}, created[4:])
+	assert.Equal(t, 2, maxSeen)
+}
+
+func TestCreateConfig_Default(t *testing.T) {
+	var c CreateConfig
+	WithServerSideApply{}.ApplyToCreateConfig(&c)
+	c.Default()
+
+	assert.Equal(t, CreateDefaultWorkers, c.Workers)
+	assert.Equal(t, DefaultFieldManager, c.ServerSideApply.FieldManager)
+	assert.False(t, c.ServerSideApply.ForceConflicts)
+}
+
+func deepCopyObjects(objs []unstructured.Unstructured) []unstructured.Unstructured {
+	out := make([]unstructured.Unstructured, len(objs))
+	for i := range objs {
+		out[i] = *objs[i].DeepCopy()
+	}
+	return out
+}
+
+func TestCluster_DeleteAndWait(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("gone", func(t *testing.T) {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test"},
+		}
+		cluster := newTestCluster(t, cm)
+
+		require.NoError(t, cluster.DeleteAndWait(ctx, cm, metav1.DeletePropagationBackground))
+		// already gone
+		require.NoError(t, cluster.DeleteAndWait(ctx, cm, metav1.DeletePropagationBackground))
+	})
+
+	newBlockedOwner := func() (owner, dependent *corev1.ConfigMap) {
+		owner = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "owner", Namespace: "test", UID: "owner-uid",
+				Finalizers: []string{
+					metav1.FinalizerDeleteDependents,
+					"test.devkube.io/cleanup",
+				},
+			},
+		}
+		dependent = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dependent", Namespace: "test",
+				Finalizers: []string{"test.devkube.io/cleanup"},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid",
+						BlockOwnerDeletion: ptr.To(true),
+					},
+				},
+			},
+		}
+		return owner, dependent
+	}
+	newDiscovery := func() *fakePreferredDiscovery {
+		return &fakePreferredDiscovery{resources: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{
+						Name: "configmaps", Kind: "ConfigMap", Namespaced: true,
+						Verbs: metav1.Verbs{"get", "list", "delete"},
+					},
+					{
+						Name: "secrets", Kind: "Secret", Namespaced: true,
+						Verbs: metav1.Verbs{"get", "list", "delete"},
+					},
+				},
+			},
+		}}
+	}
+	expectedDependents := []DependentDiagnostics{
+		{
+			GVK:        corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+			Key:        client.ObjectKey{Name: "dependent", Namespace: "test"},
+			Finalizers: []string{"test.devkube.io/cleanup"},
+		},
+	}
+
+	t.Run("blocked", func(t *testing.T) {
+		owner, dependent := newBlockedOwner()
+		cluster := newTestCluster(t, owner, dependent,
+			// not blocking
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test"}})
+		cluster.discovery = newDiscovery()
+
+		err := cluster.DeleteAndWait(ctx, owner, metav1.DeletePropagationForeground,
+			WithTimeout(50*time.Millisecond))
+		var timeoutErr *TimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		require.NoError(t, timeoutErr.DiagnosticsErr)
+		assert.Equal(t, []string{
+			metav1.FinalizerDeleteDependents,
+			"test.devkube.io/cleanup",
+		}, timeoutErr.Finalizers)
+		assert.Equal(t, expectedDependents, timeoutErr.BlockingDependents)
+		assert.Contains(t, err.Error(), "test/dependent")
+	})
+
+	t.Run("blocked with forbidden resource", func(t *testing.T) {
+		owner, dependent := newBlockedOwner()
+		cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+			List: func(
+				ctx context.Context, c client.WithWatch,
+				list client.ObjectList, opts ...client.ListOption,
+			) error {
+				if list.GetObjectKind().GroupVersionKind().Kind == "SecretList" {
+					return errors.NewForbidden(
+						corev1.Resource("secrets"), "", fmt.Errorf("not allowed"))
+				}
+				return c.List(ctx, list, opts...)
+			},
+		}, owner, dependent)
+		cluster.discovery = newDiscovery()
+
+		err := cluster.DeleteAndWait(ctx, owner, metav1.DeletePropagationForeground,
+			WithTimeout(50*time.Millisecond))
+		var timeoutErr *TimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		// dependents of other resources are still reported.
+		assert.True(t, errors.IsForbidden(timeoutErr.DiagnosticsErr), timeoutErr.DiagnosticsErr)
+		assert.Equal(t, expectedDependents, timeoutErr.BlockingDependents)
+	})
+}
+
+type fakePreferredDiscovery struct {
+	fakediscovery.FakeDiscovery
+	resources []*metav1.APIResourceList
+}
+
+func (d *fakePreferredDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return d.resources, nil
+}
+
+func TestClusterLoadObjectsFromFSFolders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifests/cm.yaml": {Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .name }}
+  namespace: default
+`)},
+	}
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+
+	err := ClusterLoadObjectsFromFSFolders{FS: fsys, Folders: []string{"manifests"}}.
+		WithOptions(WithTemplateValues{"name": "embedded"}).Init(ctx, cluster)
+	require.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cluster.CtrlClient.Get(ctx,
+		client.ObjectKey{Namespace: "default", Name: "embedded"}, cm))
+}
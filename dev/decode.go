@@ -0,0 +1,131 @@
+package dev
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// A single YAML document or JSON object within a manifest.
+type manifestDocument struct {
+	// Index of the document within the manifest, starting at 0.
+	index int
+	// Line the document content starts at, starting at 1.
+	line int
+	data []byte
+}
+
+// Splits a manifest into its YAML documents or JSON objects.
+// Documents without any content are omitted.
+func splitManifest(data []byte) ([]manifestDocument, error) {
+	if _, _, isJSON := utilyaml.GuessJSONStream(bytes.NewReader(data), 4096); isJSON {
+		return splitJSONStream(data)
+	}
+	return splitYAMLDocuments(data)
+}
+
+// Splits a stream of JSON objects.
+func splitJSONStream(data []byte) ([]manifestDocument, error) {
+	var (
+		docs    []manifestDocument
+		decoder = utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		// Offset behind the previous object.
+		offset int
+	)
+	for i := 0; ; i++ {
+		// The next object starts after the whitespace following the previous one.
+		for offset < len(data) && isJSONSpace(data[offset]) {
+			offset++
+		}
+
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding json object at index %d, line %d: %w",
+				i, lineAt(data, offset), err)
+		}
+		docs = append(docs, manifestDocument{
+			index: i, line: lineAt(data, offset), data: raw,
+		})
+		// json.Decoder returns objects verbatim.
+		offset += len(raw)
+	}
+}
+
+// Splits YAML documents separated by "---" lines.
+// Separator lines may carry trailing whitespace and comments and end with CRLF.
+func splitYAMLDocuments(data []byte) ([]manifestDocument, error) {
+	var (
+		docs   []manifestDocument
+		reader = utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+		lines  = bytes.SplitAfter(data, []byte("\n"))
+		// Index of the first line not yet returned by the reader.
+		next int
+	)
+	// Documents without content are counted, so indexes match the file.
+	for i := 0; ; i++ {
+		// The reader drops separators, but returns every other line of a document,
+		// so lines are counted alongside it.
+		for next < len(lines) && isYAMLSeparator(lines[next]) {
+			next++
+		}
+
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading yaml document at index %d, line %d: %w",
+				i, next+1, err)
+		}
+		start := next
+		next += bytes.Count(doc, []byte("\n"))
+
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		docs = append(docs, manifestDocument{
+			index: i,
+			// Documents start at their first line with actual content.
+			line: start + 1 + leadingBlankOrCommentLines(doc),
+			data: doc,
+		})
+	}
+}
+
+// Same check as utilyaml.YAMLReader, which errors for other lines starting with "---".
+func isYAMLSeparator(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("---"))
+}
+
+func leadingBlankOrCommentLines(doc []byte) int {
+	var n int
+	for _, line := range bytes.SplitAfter(doc, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// Returns the line number of the given byte offset, starting at 1.
+func lineAt(data []byte, offset int) int {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
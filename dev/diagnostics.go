@@ -0,0 +1,426 @@
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Time allowed to collect diagnostics after a wait timed out.
+	diagnosticsTimeout = 10 * time.Second
+	// Maximum number of pods to report on.
+	diagnosticsMaxPods = 10
+	// Maximum number of dependents blocking deletion to report on.
+	diagnosticsMaxDependents = 10
+)
+
+// Kinds that select their Pods via .spec.selector.
+var podOwningGroupKinds = map[schema.GroupKind]struct{}{
+	{Group: "apps", Kind: "Deployment"}:  {},
+	{Group: "apps", Kind: "StatefulSet"}: {},
+	{Group: "apps", Kind: "DaemonSet"}:   {},
+	{Group: "apps", Kind: "ReplicaSet"}:  {},
+	{Group: "batch", Kind: "Job"}:        {},
+}
+
+// TimeoutError is returned when an object did not reach the desired state in time.
+// It carries the last observed state of the object to help figuring out why.
+type TimeoutError struct {
+	GVK     schema.GroupVersionKind
+	Key     client.ObjectKey
+	Reason  string
+	Timeout time.Duration
+	// Whether the object was observed on the cluster at all.
+	Observed bool
+	// Last observed .status of the object.
+	Status map[string]interface{}
+	// Last observed .status.conditions of the object.
+	Conditions []metav1.Condition
+	// Events related to the object, oldest first.
+	Events []corev1.Event
+	// Pods belonging to workload objects.
+	Pods []PodDiagnostics
+	// Finalizers still present on an object that is being deleted.
+	Finalizers []string
+	// Dependents blocking the foreground deletion of the object.
+	BlockingDependents []DependentDiagnostics
+	// Error from collecting diagnostics, if any.
+	DiagnosticsErr error
+
+	err error
+}
+
+// PodDiagnostics summarizes the state of a Pod.
+type PodDiagnostics struct {
+	Name       string
+	Phase      corev1.PodPhase
+	Ready      bool
+	Containers []ContainerDiagnostics
+}
+
+// ContainerDiagnostics summarizes the state of a container within a Pod.
+type ContainerDiagnostics struct {
+	Name         string
+	Ready        bool
+	RestartCount int32
+	// Reason and message of the Waiting or Terminated state.
+	State, Reason, Message string
+}
+
+// DependentDiagnostics identifies an object blocking the deletion of its owner.
+type DependentDiagnostics struct {
+	GVK        schema.GroupVersionKind
+	Key        client.ObjectKey
+	Finalizers []string
+}
+
+func (e *TimeoutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "timed out after %s waiting on %s %s %s: %v",
+		e.Timeout, e.GVK, e.Key, e.Reason, e.err)
+
+	if !e.Observed {
+		b.WriteString("\nobject was never observed")
+	}
+	if len(e.Conditions) > 0 {
+		b.WriteString("\nconditions:")
+		for _, c := range e.Conditions {
+			fmt.Fprintf(&b, "\n  - %s=%s", c.Type, c.Status)
+			if len(c.Reason) > 0 {
+				fmt.Fprintf(&b, " (%s)", c.Reason)
+			}
+			if len(c.Message) > 0 {
+				fmt.Fprintf(&b, ": %s", c.Message)
+			}
+		}
+	} else if len(e.Status) > 0 {
+		status, _ := json.Marshal(e.Status)
+		fmt.Fprintf(&b, "\nstatus: %s", status)
+	}
+	if len(e.Events) > 0 {
+		b.WriteString("\nevents:")
+		for _, ev := range e.Events {
+			fmt.Fprintf(&b, "\n  - %s %s: %s", ev.Type, ev.Reason, ev.Message)
+			if ev.Count > 1 {
+				fmt.Fprintf(&b, " (x%d)", ev.Count)
+			}
+		}
+	}
+	if len(e.Pods) > 0 {
+		b.WriteString("\npods:")
+		for _, pod := range e.Pods {
+			fmt.Fprintf(&b, "\n  - %s %s ready=%t", pod.Name, pod.Phase, pod.Ready)
+			for _, c := range pod.Containers {
+				if c.Ready {
+					continue
+				}
+				fmt.Fprintf(&b, "\n    container %s %s", c.Name, c.State)
+				if len(c.Reason) > 0 {
+					fmt.Fprintf(&b, ": %s", c.Reason)
+				}
+				if len(c.Message) > 0 {
+					fmt.Fprintf(&b, ": %s", c.Message)
+				}
+				if c.RestartCount > 0 {
+					fmt.Fprintf(&b, " (restarts: %d)", c.RestartCount)
+				}
+			}
+		}
+	}
+	if len(e.Finalizers) > 0 {
+		fmt.Fprintf(&b, "\nfinalizers: %s", strings.Join(e.Finalizers, ", "))
+	}
+	if len(e.BlockingDependents) > 0 {
+		b.WriteString("\nblocking dependents:")
+		for _, d := range e.BlockingDependents {
+			fmt.Fprintf(&b, "\n  - %s %s", d.GVK, d.Key)
+			if len(d.Finalizers) > 0 {
+				fmt.Fprintf(&b, " finalizers: %s", strings.Join(d.Finalizers, ", "))
+			}
+		}
+	}
+	if e.DiagnosticsErr != nil {
+		fmt.Fprintf(&b, "\ncollecting diagnostics: %v", e.DiagnosticsErr)
+	}
+	return b.String()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.err
+}
+
+// Builds a TimeoutError from the last observed state of the object and related objects.
+func (w *Waiter) newTimeoutError(
+	ctx context.Context, c WaiterConfig,
+	object client.Object, gvk schema.GroupVersionKind,
+	waitReason string, observed bool, err error,
+) *TimeoutError {
+	timeoutErr := &TimeoutError{
+		GVK:      gvk,
+		Key:      client.ObjectKeyFromObject(object),
+		Reason:   waitReason,
+		Timeout:  c.Timeout,
+		Observed: observed,
+		err:      err,
+	}
+	if !observed {
+		return timeoutErr
+	}
+
+	// The original context has most likely expired.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+	timeoutErr.DiagnosticsErr = timeoutErr.collect(ctx, w.client, object)
+	return timeoutErr
+}
+
+func (e *TimeoutError) collect(
+	ctx context.Context, reader client.Reader, object client.Object,
+) error {
+	unstrObj := &unstructured.Unstructured{}
+	if err := copyToUnstructured(object, unstrObj); err != nil {
+		return err
+	}
+
+	e.Status, _, _ = unstructured.NestedMap(unstrObj.Object, "status")
+	if unstrObj.GetDeletionTimestamp() != nil {
+		e.Finalizers = unstrObj.GetFinalizers()
+	}
+	conditions, err := objectConditions(unstrObj)
+	if err != nil {
+		return err
+	}
+	e.Conditions = conditions
+
+	events, err := objectEvents(ctx, reader, e.GVK, object)
+	if err != nil {
+		return fmt.Errorf("listing events: %w", err)
+	}
+	e.Events = events
+
+	pods, err := objectPods(ctx, reader, e.GVK, unstrObj)
+	if err != nil {
+		return fmt.Errorf("listing pods: %w", err)
+	}
+	for i := range pods {
+		if i == diagnosticsMaxPods {
+			break
+		}
+		e.Pods = append(e.Pods, newPodDiagnostics(&pods[i]))
+	}
+	return nil
+}
+
+// Returns the objects owned by the given owner that block its foreground deletion.
+// All listable resources known to discovery are searched,
+// limited to the namespace of the owner when it is namespaced.
+// Resources that can't be listed, e.g. because of missing permissions, are skipped
+// and reported in the returned error together with the dependents found elsewhere.
+func objectBlockingDependents(
+	ctx context.Context, reader client.Reader,
+	disco discovery.DiscoveryInterface, owner client.Object,
+) ([]DependentDiagnostics, error) {
+	resourceLists, err := disco.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		// Partial results are good enough for diagnostics.
+		return nil, fmt.Errorf("discovering resources: %w", err)
+	}
+	resourceLists = discovery.FilteredBy(
+		discovery.SupportsAllVerbs{Verbs: []string{"list"}}, resourceLists)
+
+	var (
+		dependents []DependentDiagnostics
+		listErrs   []error
+	)
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			listErrs = append(listErrs, err)
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				// subresource
+				continue
+			}
+			if len(owner.GetNamespace()) > 0 && !resource.Namespaced {
+				// Cluster scoped objects can't be owned by namespaced objects.
+				continue
+			}
+
+			list := &metav1.PartialObjectMetadataList{}
+			list.SetGroupVersionKind(gv.WithKind(resource.Kind + "List"))
+			if err := reader.List(ctx, list, client.InNamespace(owner.GetNamespace())); err != nil {
+				listErrs = append(listErrs, fmt.Errorf("listing %s: %w", gv.WithKind(resource.Kind), err))
+				continue
+			}
+			for _, item := range list.Items {
+				if !isBlockingDependent(&item, owner) {
+					continue
+				}
+				dependents = append(dependents, DependentDiagnostics{
+					GVK:        gv.WithKind(resource.Kind),
+					Key:        client.ObjectKeyFromObject(&item),
+					Finalizers: item.GetFinalizers(),
+				})
+				if len(dependents) == diagnosticsMaxDependents {
+					return dependents, goerrors.Join(listErrs...)
+				}
+			}
+		}
+	}
+	return dependents, goerrors.Join(listErrs...)
+}
+
+// Reports whether obj has an owner reference to owner that blocks the owners deletion.
+func isBlockingDependent(obj, owner client.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() &&
+			ref.BlockOwnerDeletion != nil && *ref.BlockOwnerDeletion {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the events regarding the given object, oldest first.
+func objectEvents(
+	ctx context.Context, reader client.Reader,
+	gvk schema.GroupVersionKind, object client.Object,
+) ([]corev1.Event, error) {
+	namespace := object.GetNamespace()
+	if len(namespace) == 0 {
+		// Events for cluster scoped objects are recorded in the default namespace.
+		namespace = metav1.NamespaceDefault
+	}
+
+	eventList := &corev1.EventList{}
+	if err := reader.List(ctx, eventList,
+		client.InNamespace(namespace),
+		client.MatchingFields{
+			"involvedObject.name": object.GetName(),
+			"involvedObject.kind": gvk.Kind,
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	events := eventList.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+	return events, nil
+}
+
+func eventTime(ev corev1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	return ev.EventTime.Time
+}
+
+// Returns the Pods selected by workload objects or the object itself, if it is a Pod.
+func objectPods(
+	ctx context.Context, reader client.Reader,
+	gvk schema.GroupVersionKind, unstrObj *unstructured.Unstructured,
+) ([]corev1.Pod, error) {
+	if gvk.GroupKind() == (schema.GroupKind{Kind: "Pod"}) {
+		pod := corev1.Pod{}
+		if err := convertToTyped(unstrObj, &pod); err != nil {
+			return nil, err
+		}
+		return []corev1.Pod{pod}, nil
+	}
+
+	if _, ok := podOwningGroupKinds[gvk.GroupKind()]; !ok {
+		return nil, nil
+	}
+
+	selectorRaw, ok, err := unstructured.NestedMap(unstrObj.Object, "spec", "selector")
+	if err != nil || !ok {
+		return nil, err
+	}
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		selectorRaw, labelSelector); err != nil {
+		return nil, fmt.Errorf("can't convert .spec.selector: %w", err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid .spec.selector: %w", err)
+	}
+
+	podList := &corev1.PodList{}
+	if err := reader.List(ctx, podList,
+		client.InNamespace(unstrObj.GetNamespace()),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+func newPodDiagnostics(pod *corev1.Pod) PodDiagnostics {
+	d := PodDiagnostics{
+		Name:  pod.Name,
+		Phase: pod.Status.Phase,
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			d.Ready = cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	var statuses []corev1.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		c := ContainerDiagnostics{
+			Name:         status.Name,
+			Ready:        status.Ready,
+			RestartCount: status.RestartCount,
+		}
+		switch {
+		case status.State.Waiting != nil:
+			c.State = "waiting"
+			c.Reason = status.State.Waiting.Reason
+			c.Message = status.State.Waiting.Message
+		case status.State.Terminated != nil:
+			c.State = "terminated"
+			c.Reason = status.State.Terminated.Reason
+			c.Message = status.State.Terminated.Message
+		case status.State.Running != nil:
+			c.State = "running"
+		}
+		d.Containers = append(d.Containers, c)
+	}
+	return d
+}
+
+// Copies the state of the given object into the unstructured dst.
+func copyToUnstructured(src client.Object, dst *unstructured.Unstructured) error {
+	if unstrSrc, ok := src.(*unstructured.Unstructured); ok {
+		dst.Object = unstrSrc.DeepCopy().Object
+		return nil
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(src)
+	if err != nil {
+		return fmt.Errorf("can't convert to unstructured: %w", err)
+	}
+	dst.Object = obj
+	return nil
+}
@@ -0,0 +1,130 @@
+package dev
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWaiter_TimeoutError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	ctx := context.Background()
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "app"},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{
+				{
+					Type:    appsv1.DeploymentAvailable,
+					Status:  corev1.ConditionFalse,
+					Reason:  "MinimumReplicasUnavailable",
+					Message: "Deployment does not have minimum availability.",
+				},
+			},
+		},
+	}
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: "app.1", Namespace: "test"},
+		InvolvedObject: corev1.ObjectReference{
+			Kind: "Deployment", Name: "app", Namespace: "test",
+		},
+		Type:    corev1.EventTypeNormal,
+		Reason:  "ScalingReplicaSet",
+		Message: "Scaled up replica set app-1 to 1",
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "app-1-abc", Namespace: "test",
+			Labels: map[string]string{"app": "app"},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "app",
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{
+							Reason:  "ImagePullBackOff",
+							Message: `Back-off pulling image "app:latest"`,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(deploy, event, pod).
+		WithIndex(&corev1.Event{}, "involvedObject.name", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Event).InvolvedObject.Name}
+		}).
+		WithIndex(&corev1.Event{}, "involvedObject.kind", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Event).InvolvedObject.Kind}
+		}).
+		Build()
+	w := NewWaiter(c, scheme,
+		WithInterval(10*time.Millisecond),
+		WithTimeout(50*time.Millisecond))
+
+	err := w.WaitForReadiness(ctx, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.NoError(t, timeoutErr.DiagnosticsErr)
+	assert.True(t, timeoutErr.Observed)
+	assert.Equal(t, client.ObjectKey{Name: "app", Namespace: "test"}, timeoutErr.Key)
+	if assert.Len(t, timeoutErr.Conditions, 1) {
+		assert.Equal(t, "MinimumReplicasUnavailable", timeoutErr.Conditions[0].Reason)
+	}
+	if assert.Len(t, timeoutErr.Events, 1) {
+		assert.Equal(t, "ScalingReplicaSet", timeoutErr.Events[0].Reason)
+	}
+	if assert.Len(t, timeoutErr.Pods, 1) {
+		assert.Equal(t, []ContainerDiagnostics{
+			{
+				Name:    "app",
+				State:   "waiting",
+				Reason:  "ImagePullBackOff",
+				Message: `Back-off pulling image "app:latest"`,
+			},
+		}, timeoutErr.Pods[0].Containers)
+	}
+	assert.Contains(t, err.Error(), "ImagePullBackOff")
+}
+
+func TestWaiter_TimeoutError_NeverObserved(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	w := NewWaiter(c, scheme,
+		WithInterval(10*time.Millisecond),
+		WithTimeout(50*time.Millisecond))
+
+	err := w.WaitForReadiness(context.Background(), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+	})
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.False(t, timeoutErr.Observed)
+	assert.Contains(t, err.Error(), "never observed")
+}
@@ -0,0 +1,197 @@
+package dev
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DiffResult describes what applying an object would do.
+type DiffResult string
+
+const (
+	DiffCreated   DiffResult = "created"
+	DiffChanged   DiffResult = "changed"
+	DiffUnchanged DiffResult = "unchanged"
+)
+
+// ObjectDiff describes what applying an object would change on the cluster.
+type ObjectDiff struct {
+	GVK    schema.GroupVersionKind
+	Key    client.ObjectKey
+	Result DiffResult
+	// Unified YAML diff between the live and the applied object.
+	// Managed fields and status are ignored.
+	// Contains the whole object for created objects.
+	Diff string
+}
+
+// DiffReport collects the differences found in diff mode, see WithDiff.
+type DiffReport struct {
+	mu      sync.Mutex
+	Objects []ObjectDiff
+}
+
+func (r *DiffReport) add(diffs ...ObjectDiff) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Objects = append(r.Objects, diffs...)
+}
+
+// Returns the objects with the given result.
+func (r *DiffReport) Filter(result DiffResult) []ObjectDiff {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []ObjectDiff
+	for _, d := range r.Objects {
+		if d.Result == result {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Returns a human readable summary, including the diffs of changed objects.
+func (r *DiffReport) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	for _, d := range r.Objects {
+		fmt.Fprintf(&b, "%s %s %s\n", d.GVK, d.Key, d.Result)
+		if d.Result == DiffChanged {
+			b.WriteString(d.Diff)
+		}
+	}
+	return b.String()
+}
+
+// Server-side dry-runs applying the given objects and reports
+// which objects would be created, changed or stay unchanged.
+// Nothing is mutated on the cluster.
+// Objects that can't be dry-run, because their Namespace or CRD does not exist yet,
+// are reported as created without server-side validation.
+func (c *Cluster) Diff(
+	ctx context.Context, objects []unstructured.Unstructured,
+	opts ...CreateOption,
+) ([]ObjectDiff, error) {
+	var config CreateConfig
+	for _, opt := range opts {
+		opt.ApplyToCreateConfig(&config)
+	}
+	config.Default()
+
+	applyConfig := ServerSideApplyConfig{FieldManager: DefaultFieldManager}
+	if config.ServerSideApply != nil {
+		applyConfig = *config.ServerSideApply
+	}
+
+	var (
+		diffs []ObjectDiff
+		errs  []error
+	)
+	for i := range objects {
+		diff, err := c.diff(ctx, &objects[i], applyConfig)
+		if err != nil {
+			errs = append(errs, wrapWithSourceLocation(&objects[i], err))
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, goerrors.Join(errs...)
+}
+
+func (c *Cluster) diff(
+	ctx context.Context, object *unstructured.Unstructured,
+	config ServerSideApplyConfig,
+) (ObjectDiff, error) {
+	d := ObjectDiff{
+		GVK: object.GroupVersionKind(),
+		Key: client.ObjectKeyFromObject(object),
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(d.GVK)
+	err := c.CtrlClient.Get(ctx, d.Key, live)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return d, fmt.Errorf("getting %s %s: %w", d.GVK, d.Key, err)
+	}
+
+	applied := object.DeepCopy()
+	RemoveSourceLocation(applied)
+	applied.SetManagedFields(nil)
+	applied.SetResourceVersion("")
+	// Ownership is always forced, as nothing is persisted.
+	// Otherwise fields of objects created without server-side apply
+	// conflict with their original field manager instead of showing up as changed.
+	err = c.CtrlClient.Patch(ctx, applied, client.Apply,
+		client.FieldOwner(config.FieldManager), client.ForceOwnership, client.DryRunAll)
+	switch {
+	case !exists && (errors.IsNotFound(err) || meta.IsNoMatchError(err)):
+		// Namespace or CRD is not there yet.
+		applied = object.DeepCopy()
+		RemoveSourceLocation(applied)
+	case err != nil:
+		return d, fmt.Errorf("dry-run applying %s %s: %w", d.GVK, d.Key, err)
+	}
+
+	appliedYAML, err := normalizedYAML(applied)
+	if err != nil {
+		return d, err
+	}
+	var liveYAML string
+	if exists {
+		if liveYAML, err = normalizedYAML(live); err != nil {
+			return d, err
+		}
+	}
+
+	switch {
+	case !exists:
+		d.Result = DiffCreated
+	case liveYAML == appliedYAML:
+		d.Result = DiffUnchanged
+		return d, nil
+	default:
+		d.Result = DiffChanged
+	}
+
+	d.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(liveYAML),
+		B:        difflib.SplitLines(appliedYAML),
+		FromFile: "live",
+		ToFile:   "applied",
+		Context:  3,
+	})
+	if err != nil {
+		return d, fmt.Errorf("diffing %s %s: %w", d.GVK, d.Key, err)
+	}
+	return d, nil
+}
+
+// Returns the object as YAML without fields that are not managed by the user.
+func normalizedYAML(obj *unstructured.Unstructured) (string, error) {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	out, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", fmt.Errorf("marshalling %s: %w", obj.GroupVersionKind(), err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,155 @@
+package dev
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newTestConfigMap(name, value string) *unstructured.Unstructured {
+	obj := newTestObject("v1", "ConfigMap", name)
+	obj.SetNamespace("test")
+	_ = unstructured.SetNestedField(obj.Object, value, "data", "key")
+	return &obj
+}
+
+// Simulates the API server dry-run applying objects, as the fake client does not support server-side apply.
+// Applying to existing objects conflicts with the field manager that created them, unless ownership is forced.
+func fakeDryRunApply(t *testing.T) func(
+	ctx context.Context, c client.WithWatch, obj client.Object,
+	patch client.Patch, opts ...client.PatchOption,
+) error {
+	t.Helper()
+	return func(
+		ctx context.Context, c client.WithWatch, obj client.Object,
+		patch client.Patch, opts ...client.PatchOption,
+	) error {
+		assert.Equal(t, types.ApplyPatchType, patch.Type())
+		patchOpts := &client.PatchOptions{}
+		patchOpts.ApplyOptions(opts)
+		assert.Equal(t, []string{"All"}, patchOpts.DryRun)
+
+		if obj.GetNamespace() == "missing" {
+			return errors.NewNotFound(
+				schema.GroupResource{Resource: "namespaces"}, "missing")
+		}
+
+		// simulate the server merging the object into the live one.
+		unstrObj := obj.(*unstructured.Unstructured)
+		current := &unstructured.Unstructured{}
+		current.SetGroupVersionKind(unstrObj.GroupVersionKind())
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), current)
+		if errors.IsNotFound(err) {
+			obj.SetUID("1234")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if patchOpts.Force == nil || !*patchOpts.Force {
+			return errors.NewApplyConflict([]metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "before-first-apply": .data.key`,
+					Field:   ".data.key",
+				},
+			}, "Apply failed with 1 conflict")
+		}
+		current.Object["data"] = unstrObj.Object["data"]
+		current.SetResourceVersion("1000")
+		unstrObj.Object = current.Object
+		return nil
+	}
+}
+
+func TestCluster_Diff(t *testing.T) {
+	ctx := context.Background()
+
+	live := newTestConfigMap("changed", "old")
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Patch: fakeDryRunApply(t),
+	}, live, newTestConfigMap("unchanged", "value"))
+
+	missingNamespace := newTestConfigMap("pending", "value")
+	missingNamespace.SetNamespace("missing")
+
+	report := &DiffReport{}
+	require.NoError(t, cluster.createObjectsFromSource(ctx, "test", []unstructured.Unstructured{
+		*newTestConfigMap("changed", "new"),
+		*newTestConfigMap("unchanged", "value"),
+		*newTestConfigMap("created", "value"),
+		*missingNamespace,
+	}, WithDiff{report}))
+
+	results := map[string]DiffResult{}
+	for _, d := range report.Objects {
+		results[d.Key.Name] = d.Result
+	}
+	assert.Equal(t, map[string]DiffResult{
+		"changed":   DiffChanged,
+		"unchanged": DiffUnchanged,
+		"created":   DiffCreated,
+		"pending":   DiffCreated,
+	}, results)
+
+	changed := report.Filter(DiffChanged)
+	require.Len(t, changed, 1)
+	assert.Contains(t, changed[0].Diff, "-  key: old\n+  key: new\n")
+	assert.NotContains(t, changed[0].Diff, "resourceVersion")
+	assert.Contains(t, report.String(), "test/changed changed")
+
+	// nothing has been created.
+	err := cluster.CtrlClient.Get(ctx, client.ObjectKey{Name: "created", Namespace: "test"},
+		newTestConfigMap("created", ""))
+	assert.True(t, errors.IsNotFound(err), err)
+}
+
+func TestCluster_Diff_CreatedObject(t *testing.T) {
+	ctx := context.Background()
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Patch: fakeDryRunApply(t),
+	})
+
+	// created without server-side apply, so fields are owned by another field manager.
+	require.NoError(t, cluster.createObjectsFromSource(ctx, "test", []unstructured.Unstructured{
+		*newTestConfigMap("cm", "old"),
+	}))
+
+	report := &DiffReport{}
+	require.NoError(t, cluster.createObjectsFromSource(ctx, "test", []unstructured.Unstructured{
+		*newTestConfigMap("cm", "new"),
+	}, WithDiff{report}))
+
+	changed := report.Filter(DiffChanged)
+	require.Len(t, changed, 1)
+	assert.Contains(t, changed[0].Diff, "-  key: old\n+  key: new\n")
+}
+
+func TestCluster_CreateAndWaitFromFiles_Diff(t *testing.T) {
+	ctx := context.Background()
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Patch: fakeDryRunApply(t),
+	})
+
+	report := &DiffReport{}
+	require.NoError(t, cluster.CreateAndWaitFromFiles(ctx,
+		[]string{"testdata/deployment.yaml"}, WithDiff{report}))
+	assert.Len(t, report.Filter(DiffCreated), 1)
+
+	// nothing has been created.
+	err := cluster.CtrlClient.Get(ctx, client.ObjectKey{
+		Namespace: "test-namespace", Name: "test-deployment",
+	}, &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1", "kind": "Deployment",
+	}})
+	assert.True(t, errors.IsNotFound(err), err)
+}
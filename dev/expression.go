@@ -0,0 +1,223 @@
+package dev
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadinessExpression declares the readiness of a GroupKind using expressions,
+// so readiness checks can be configured without writing Go code.
+// When both JSONPath and CEL are set, both have to be satisfied.
+type ReadinessExpression struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	// JSONPath template evaluated against the object, e.g. "{.status.phase}".
+	// Surrounding braces are optional.
+	JSONPath string `json:"jsonPath,omitempty"`
+	// Value the JSONPath result has to be equal to.
+	Value string `json:"value,omitempty"`
+	// CEL expression that has to evaluate to true.
+	// The whole object is available as "object",
+	// top-level fields as "metadata", "spec" and "status", e.g.
+	// `status.phase == "Running" && size(status.endpoints) > 0`.
+	CEL string `json:"cel,omitempty"`
+}
+
+// Compiles the expressions into a ReadinessCheckFunc.
+func (e ReadinessExpression) ReadinessCheck() (ReadinessCheckFunc, error) {
+	var checks []func(obj *unstructured.Unstructured) (bool, error)
+	if len(e.JSONPath) > 0 {
+		check, err := newJSONPathCheck(e.JSONPath, e.Value)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	if len(e.CEL) > 0 {
+		check, err := newCELCheck(e.CEL)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("neither jsonPath nor cel expression set for %s", e.GroupKind())
+	}
+
+	return func(
+		_ context.Context, obj client.Object,
+		_ client.Reader, _ *runtime.Scheme,
+	) (done bool, err error) {
+		unstrObj := &unstructured.Unstructured{}
+		if err := copyToUnstructured(obj, unstrObj); err != nil {
+			return false, err
+		}
+		for _, check := range checks {
+			if done, err := check(unstrObj); err != nil || !done {
+				return false, err
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+// Returns the GroupKind the expression applies to.
+func (e ReadinessExpression) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: e.Group, Kind: e.Kind}
+}
+
+// Compiles the given expressions into ReadinessChecks,
+// e.g. to be passed to WithReadinessChecks.
+func ReadinessChecksFromExpressions(exprs ...ReadinessExpression) (ReadinessChecks, error) {
+	checks := ReadinessChecks{}
+	for _, expr := range exprs {
+		check, err := expr.ReadinessCheck()
+		if err != nil {
+			return nil, fmt.Errorf("readiness expression for %s: %w", expr.GroupKind(), err)
+		}
+		checks[expr.GroupKind()] = check
+	}
+	return checks, nil
+}
+
+// Waits for the given JSONPath template to evaluate to value on the object.
+func (w *Waiter) WaitForJSONPath(
+	ctx context.Context, object client.Object,
+	jsonPath, value string, opts ...WaitOption,
+) error {
+	check, err := newJSONPathCheck(jsonPath, value)
+	if err != nil {
+		return err
+	}
+	return w.waitForUnstructured(ctx, object,
+		fmt.Sprintf("for %s to be %q", jsonPath, value), check, opts...)
+}
+
+// Waits for the given CEL expression to evaluate to true on the object.
+// See ReadinessExpression for the available variables.
+func (w *Waiter) WaitForCEL(
+	ctx context.Context, object client.Object,
+	expression string, opts ...WaitOption,
+) error {
+	check, err := newCELCheck(expression)
+	if err != nil {
+		return err
+	}
+	return w.waitForUnstructured(ctx, object,
+		fmt.Sprintf("for %q", expression), check, opts...)
+}
+
+func (w *Waiter) waitForUnstructured(
+	ctx context.Context, object client.Object, waitReason string,
+	check func(obj *unstructured.Unstructured) (bool, error),
+	opts ...WaitOption,
+) error {
+	return w.WaitForObject(ctx, object, waitReason,
+		func(obj client.Object) (done bool, err error) {
+			unstrObj := &unstructured.Unstructured{}
+			if err := copyToUnstructured(obj, unstrObj); err != nil {
+				return false, err
+			}
+			return check(unstrObj)
+		}, opts...)
+}
+
+func newJSONPathCheck(
+	jsonPath, value string,
+) (func(obj *unstructured.Unstructured) (bool, error), error) {
+	if !strings.HasPrefix(jsonPath, "{") {
+		jsonPath = "{" + jsonPath + "}"
+	}
+
+	jp := jsonpath.New("readiness")
+	if err := jp.Parse(jsonPath); err != nil {
+		return nil, fmt.Errorf("parsing jsonPath %q: %w", jsonPath, err)
+	}
+	// Fields may not have been reported yet.
+	jp.AllowMissingKeys(true)
+
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		var out bytes.Buffer
+		if err := jp.Execute(&out, obj.Object); err != nil {
+			return false, fmt.Errorf("executing jsonPath %q: %w", jsonPath, err)
+		}
+		return out.String() == value, nil
+	}, nil
+}
+
+// Top-level object fields available as CEL variables.
+var celObjectFields = []string{"metadata", "spec", "status"}
+
+func newCELCheck(
+	expression string,
+) (func(obj *unstructured.Unstructured) (bool, error), error) {
+	envOpts := []cel.EnvOption{cel.Variable("object", cel.DynType)}
+	for _, field := range celObjectFields {
+		envOpts = append(envOpts, cel.Variable(field, cel.DynType))
+	}
+	env, err := cel.NewEnv(envOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("compiling CEL expression %q: %w", expression, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf(
+			"CEL expression %q must evaluate to bool, not %s", expression, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL program: %w", err)
+	}
+
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		vars := map[string]interface{}{"object": obj.Object}
+		for _, field := range celObjectFields {
+			value, ok := obj.Object[field]
+			if !ok {
+				value = map[string]interface{}{}
+			}
+			vars[field] = value
+		}
+
+		out, _, err := program.Eval(vars)
+		if isCELMissingFieldError(err) {
+			// retry until the object reports the fields.
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("evaluating CEL expression %q: %w", expression, err)
+		}
+		done, ok := out.Value().(bool)
+		if !ok {
+			return false, fmt.Errorf(
+				"CEL expression %q evaluated to %s, not bool", expression, out.Type())
+		}
+		return done, nil
+	}, nil
+}
+
+// Reports whether a CEL evaluation error is caused by referencing fields
+// that have not been reported yet, e.g. status.conditions[0] of a new object.
+// cel-go does not export typed errors for these cases, so messages are matched.
+func isCELMissingFieldError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, "no such key") ||
+		strings.HasPrefix(msg, "no such attribute") ||
+		strings.HasPrefix(msg, "index out of bounds")
+}
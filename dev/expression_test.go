@@ -0,0 +1,127 @@
+package dev
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReadinessExpression_ReadinessCheck(t *testing.T) {
+	ready := newTestCR("Running")
+	ready.Object["status"].(map[string]interface{})["endpoints"] = []interface{}{"10.0.0.1"}
+	noEndpoints := newTestCR("Running")
+	noEndpoints.Object["status"].(map[string]interface{})["endpoints"] = []interface{}{}
+	noStatus := newTestCR("")
+
+	tests := []struct {
+		name    string
+		expr    ReadinessExpression
+		results map[string]bool
+	}{
+		{
+			name: "jsonPath",
+			expr: ReadinessExpression{JSONPath: "{.status.phase}", Value: "Running"},
+			results: map[string]bool{
+				"ready": true, "noEndpoints": true, "noStatus": false,
+			},
+		},
+		{
+			name: "relaxed jsonPath",
+			expr: ReadinessExpression{JSONPath: ".status.phase", Value: "Running"},
+			results: map[string]bool{
+				"ready": true, "noEndpoints": true, "noStatus": false,
+			},
+		},
+		{
+			name: "cel",
+			expr: ReadinessExpression{
+				CEL: `status.phase == "Running" && size(status.endpoints) > 0`,
+			},
+			results: map[string]bool{
+				"ready": true, "noEndpoints": false, "noStatus": false,
+			},
+		},
+		{
+			name: "cel and jsonPath",
+			expr: ReadinessExpression{
+				JSONPath: "{.metadata.name}", Value: "gouda",
+				CEL: `object.status.phase == "Running"`,
+			},
+			results: map[string]bool{
+				"ready": true, "noEndpoints": true, "noStatus": false,
+			},
+		},
+	}
+
+	objects := map[string]*unstructured.Unstructured{
+		"ready": ready, "noEndpoints": noEndpoints, "noStatus": noStatus,
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check, err := test.expr.ReadinessCheck()
+			require.NoError(t, err)
+
+			for name, result := range test.results {
+				done, err := check(context.Background(),
+					objects[name], nil, nil)
+				require.NoError(t, err, name)
+				assert.Equal(t, result, done, name)
+			}
+		})
+	}
+}
+
+func TestReadinessExpression_ReadinessCheck_Invalid(t *testing.T) {
+	for _, expr := range []ReadinessExpression{
+		{},
+		{JSONPath: "{.status.phase"},
+		{CEL: `status.phase ==`},
+		{CEL: `"not a bool"`},
+	} {
+		_, err := expr.ReadinessCheck()
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestReadinessExpression_ReadinessCheck_EvalError(t *testing.T) {
+	check, err := ReadinessExpression{CEL: `status.phase > 1`}.ReadinessCheck()
+	require.NoError(t, err)
+
+	// type errors fail fast instead of being retried until the timeout.
+	_, err = check(context.Background(), newTestCR("Running"), nil, nil)
+	assert.ErrorContains(t, err, "no such overload")
+
+	// missing fields are retried.
+	check, err = ReadinessExpression{CEL: `status.conditions[0].status == "True"`}.ReadinessCheck()
+	require.NoError(t, err)
+	done, err := check(context.Background(), newTestCR(""), nil, nil)
+	require.NoError(t, err)
+	assert.False(t, done)
+}
+
+func TestReadinessChecksFromExpressions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(newTestCR("Ripe")).Build()
+
+	checks, err := ReadinessChecksFromExpressions(ReadinessExpression{
+		Group: testCRGK.Group, Kind: testCRGK.Kind,
+		CEL: `status.phase == "Ripe"`,
+	})
+	require.NoError(t, err)
+
+	w := NewWaiter(c, scheme,
+		WithReadinessChecks(checks),
+		WithTimeout(time.Second))
+	require.NoError(t, w.WaitForReadiness(context.Background(), newTestCR("")))
+	require.NoError(t, w.WaitForCEL(context.Background(), newTestCR(""),
+		`metadata.name == "gouda"`))
+	require.NoError(t, w.WaitForJSONPath(context.Background(), newTestCR(""),
+		"{.status.phase}", "Ripe"))
+}
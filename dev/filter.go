@@ -0,0 +1,79 @@
+package dev
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Filters objects, e.g. to drop PodDisruptionBudgets or webhook configurations
+// of upstream bundles that are not needed in a test cluster.
+// Objects are kept when they match any Include target, or when there are none,
+// and match no Exclude target.
+type ObjectFilter struct {
+	Include []PatchTarget
+	Exclude []PatchTarget
+}
+
+func (f ObjectFilter) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	include, err := newTargetMatchers(f.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := newTargetMatchers(f.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []unstructured.Unstructured
+	for i := range objects {
+		included, err := include.matchAny(&objects[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(include) > 0 && !included {
+			continue
+		}
+		excluded, err := exclude.matchAny(&objects[i])
+		if err != nil {
+			return nil, err
+		}
+		if !excluded {
+			out = append(out, objects[i])
+		}
+	}
+	return out, nil
+}
+
+// PatchTarget with its selectors parsed.
+type targetMatcher struct {
+	target                       PatchTarget
+	selector, annotationSelector labels.Selector
+}
+
+type targetMatchers []targetMatcher
+
+func newTargetMatchers(targets []PatchTarget) (targetMatchers, error) {
+	matchers := make(targetMatchers, len(targets))
+	for i, target := range targets {
+		selector, annotationSelector, err := target.selectors()
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = targetMatcher{
+			target: target, selector: selector, annotationSelector: annotationSelector,
+		}
+	}
+	return matchers, nil
+}
+
+func (m targetMatchers) matchAny(obj *unstructured.Unstructured) (bool, error) {
+	for _, matcher := range m {
+		ok, err := matcher.target.matches(obj, matcher.selector, matcher.annotationSelector)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,112 @@
+package dev
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newFilterTestObjects() []unstructured.Unstructured {
+	pdb := newTestObject("policy/v1", "PodDisruptionBudget", "operator")
+	pdb.SetNamespace("operator-system")
+	webhook := newTestObject("admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", "operator-webhook")
+	webhook.SetLabels(map[string]string{"app": "operator"})
+	cm := newTestObject("v1", "ConfigMap", "operator-config")
+	cm.SetNamespace("operator-system")
+	cm.SetAnnotations(map[string]string{"test.devkube.io/optional": "true"})
+	deployment := newTestObject("apps/v1", "Deployment", "operator")
+	deployment.SetNamespace("operator-system")
+	deployment.SetLabels(map[string]string{"app": "operator"})
+	return []unstructured.Unstructured{pdb, webhook, cm, deployment}
+}
+
+func TestObjectFilter_Include(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   PatchTarget
+		expected []string
+	}{
+		{
+			name:     "empty",
+			expected: []string{"operator", "operator-webhook", "operator-config", "operator"},
+		},
+		{
+			name:     "group and kind",
+			target:   PatchTarget{Group: "policy", Kind: "PodDisruptionBudget"},
+			expected: []string{"operator"},
+		},
+		{
+			name:     "name glob",
+			target:   PatchTarget{Name: "operator-*"},
+			expected: []string{"operator-webhook", "operator-config"},
+		},
+		{
+			name:     "namespace and label selector",
+			target:   PatchTarget{Namespace: "operator-system", LabelSelector: "app=operator"},
+			expected: []string{"operator"},
+		},
+		{
+			name:     "annotation",
+			target:   PatchTarget{AnnotationSelector: "test.devkube.io/optional"},
+			expected: []string{"operator-config"},
+		},
+		{
+			name:     "annotation value",
+			target:   PatchTarget{AnnotationSelector: "test.devkube.io/optional=false"},
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects, err := ObjectFilter{
+				Include: []PatchTarget{test.target},
+			}.Transform(newFilterTestObjects())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, objectNames(objects))
+		})
+	}
+
+	_, err := ObjectFilter{
+		Exclude: []PatchTarget{{AnnotationSelector: "!!"}},
+	}.Transform(newFilterTestObjects())
+	require.ErrorContains(t, err, "parsing annotation selector")
+}
+
+func TestObjectFilter(t *testing.T) {
+	objects, err := ObjectFilter{
+		Exclude: []PatchTarget{
+			{Kind: "PodDisruptionBudget"},
+			{Group: "admissionregistration.k8s.io"},
+		},
+	}.Transform(newFilterTestObjects())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"operator-config", "operator"}, objectNames(objects))
+
+	objects, err = ObjectFilter{
+		Include: []PatchTarget{{Namespace: "operator-system"}},
+		Exclude: []PatchTarget{{Kind: "PodDisruptionBudget"}},
+	}.Transform(newFilterTestObjects())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"operator-config", "operator"}, objectNames(objects))
+}
+
+func TestClusterLoadObjectsFromFiles_ObjectFilter(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+
+	err := ClusterLoadObjectsFromFiles{"testdata/deployment.yaml"}.WithOptions(
+		WithObjectFilter{Exclude: []PatchTarget{{Kind: "Deployment"}}},
+	).Init(ctx, cluster)
+	require.NoError(t, err)
+
+	err = cluster.CtrlClient.Get(ctx, client.ObjectKey{
+		Namespace: "test-namespace", Name: "test-deployment",
+	}, &appsv1.Deployment{})
+	assert.True(t, errors.IsNotFound(err), err)
+}
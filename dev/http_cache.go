@@ -0,0 +1,254 @@
+package dev
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+type HttpCacheConfig struct {
+	// Directory responses are cached in.
+	Dir string
+	// Headers sent with every request, e.g. for authentication.
+	Headers http.Header
+	// Only serve responses from the cache, never go to the network.
+	Offline bool
+	// Backoff between attempts to download a url.
+	// Steps is the maximum number of attempts.
+	Backoff wait.Backoff
+	// Client used for downloads, defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Defaults unset Backoff fields individually,
+// e.g. Backoff{Steps: 1} disables retries but keeps the default Duration.
+func (c *HttpCacheConfig) Default() {
+	if c.Backoff.Duration == 0 {
+		c.Backoff.Duration = 500 * time.Millisecond
+	}
+	if c.Backoff.Factor == 0 {
+		c.Backoff.Factor = 2
+	}
+	if c.Backoff.Jitter == 0 {
+		c.Backoff.Jitter = 0.1
+	}
+	if c.Backoff.Steps == 0 {
+		c.Backoff.Steps = 4
+	}
+	if c.Backoff.Cap == 0 {
+		c.Backoff.Cap = 10 * time.Second
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+}
+
+type HttpCacheOption interface {
+	ApplyToHttpCacheConfig(c *HttpCacheConfig)
+}
+
+// HttpCache downloads and caches manifests on disk,
+// so they are only downloaded again when their pin does not match
+// and can be served without network access in offline mode.
+type HttpCache struct {
+	HttpCacheConfig
+}
+
+func NewHttpCache(dir string, opts ...HttpCacheOption) *HttpCache {
+	h := &HttpCache{
+		HttpCacheConfig: HttpCacheConfig{
+			Dir: dir,
+		},
+	}
+	for _, opt := range opts {
+		opt.ApplyToHttpCacheConfig(&h.HttpCacheConfig)
+	}
+	h.HttpCacheConfig.Default()
+	return h
+}
+
+// HttpResource references content to download.
+type HttpResource struct {
+	URL string
+	// Hex encoded sha256 checksum the content has to match, optional.
+	// Pinned content is served from the cache without checking for updates.
+	SHA256 string
+	// Headers sent in addition to the headers of the cache, e.g. for authentication.
+	Headers http.Header
+}
+
+// Returns the content of the given resource.
+// Pinned resources are served from the cache when present,
+// other resources are downloaded again and the cache is updated.
+// In offline mode, resources are only served from the cache.
+func (h *HttpCache) Get(ctx context.Context, res HttpResource) ([]byte, error) {
+	cachePath := h.cachePath(res.URL)
+	if res.SHA256 != "" || h.Offline {
+		content, err := os.ReadFile(cachePath)
+		switch {
+		case err == nil:
+			if err := verifySHA256(content, res.SHA256); err != nil {
+				if h.Offline {
+					return nil, fmt.Errorf("cached %q: %w", res.URL, err)
+				}
+				// stale cache entry, download again.
+				break
+			}
+			return content, nil
+		case !goerrors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("reading cached %q: %w", res.URL, err)
+		case h.Offline:
+			return nil, fmt.Errorf("%q is not cached and offline mode is enabled", res.URL)
+		}
+	}
+
+	content, err := h.download(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySHA256(content, res.SHA256); err != nil {
+		return nil, fmt.Errorf("getting %q: %w", res.URL, err)
+	}
+	if err := h.store(cachePath, content); err != nil {
+		return nil, fmt.Errorf("caching %q: %w", res.URL, err)
+	}
+	return content, nil
+}
+
+// Returns an ObjectSource loading objects from the given resources through the cache.
+func (h *HttpCache) Source(resources ...HttpResource) ObjectSource {
+	return CachingHttpSource{Cache: h, Resources: resources}
+}
+
+// Downloads the resource, retrying on network errors and retriable status codes.
+func (h *HttpCache) download(ctx context.Context, res HttpResource) ([]byte, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	backoff := h.Backoff
+	for {
+		content, retry, err := h.downloadOnce(ctx, res)
+		if err == nil {
+			return content, nil
+		}
+		if !retry || backoff.Steps <= 1 {
+			return nil, err
+		}
+
+		delay := backoff.Step()
+		log.Info(fmt.Sprintf("retrying %q in %s: %v", res.URL, delay, err))
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (h *HttpCache) downloadOnce(
+	ctx context.Context, res HttpResource,
+) (content []byte, retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, res.URL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("creating request: %w", err)
+	}
+	for _, headers := range []http.Header{h.Headers, res.Headers} {
+		for key, values := range headers {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("getting %q: %w", res.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retry, fmt.Errorf("getting %q: unexpected status %s", res.URL, resp.Status)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, resp.Body); err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("reading response %q: %w", res.URL, err)
+	}
+	return buf.Bytes(), false, nil
+}
+
+// Returns the path content of the given url is cached at.
+func (h *HttpCache) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return path.Join(h.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Writes the cache entry atomically, so concurrent readers never see partial content.
+func (h *HttpCache) store(cachePath string, content []byte) error {
+	if err := os.MkdirAll(h.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(h.Dir, ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
+
+// Checks that content matches the hex encoded sha256 checksum, if one is given.
+func verifySHA256(content []byte, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	sum := sha256.Sum256(content)
+	if actual := hex.EncodeToString(sum[:]); actual != strings.ToLower(expected) {
+		return fmt.Errorf("sha256 mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// Loads objects from the given resources through an HttpCache.
+type CachingHttpSource struct {
+	Cache     *HttpCache
+	Resources []HttpResource
+}
+
+func (s CachingHttpSource) LoadObjects(
+	ctx context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, res := range s.Resources {
+		content, err := s.Cache.Get(ctx, res)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from %q: %w", res.URL, err)
+		}
+		objs, err := loadKubernetesObjectsFromBytes(res.URL, content, opts...)
+		if err == nil {
+			objs, err = transformLoadedObjects(objs, opts...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from %q: %w", res.URL, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s CachingHttpSource) String() string { return "http" }
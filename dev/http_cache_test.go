@@ -0,0 +1,149 @@
+package dev
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const testHttpManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cached\n"
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestHttpServer(t *testing.T, requests *atomic.Int32, failures int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(testHttpManifest))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestHttpCache(dir string, opts ...HttpCacheOption) *HttpCache {
+	return NewHttpCache(dir, append([]HttpCacheOption{
+		WithHttpHeaders{"Authorization": {"Bearer token"}},
+		WithRetryBackoff(wait.Backoff{Duration: time.Millisecond, Steps: 3}),
+	}, opts...)...)
+}
+
+func TestHttpCache_Get(t *testing.T) {
+	var requests atomic.Int32
+	srv := newTestHttpServer(t, &requests, 2)
+	dir := t.TempDir()
+	ctx := context.Background()
+	res := HttpResource{URL: srv.URL + "/cm.yaml", SHA256: sha256Hex(testHttpManifest)}
+
+	// retries until the server recovers.
+	content, err := newTestHttpCache(dir).Get(ctx, res)
+	require.NoError(t, err)
+	assert.Equal(t, testHttpManifest, string(content))
+	assert.Equal(t, int32(3), requests.Load())
+
+	// pinned content is served from the cache.
+	content, err = newTestHttpCache(dir).Get(ctx, res)
+	require.NoError(t, err)
+	assert.Equal(t, testHttpManifest, string(content))
+	assert.Equal(t, int32(3), requests.Load())
+
+	// offline mode serves from the cache only.
+	srv.Close()
+	offline := newTestHttpCache(dir, WithOffline(true))
+	content, err = offline.Get(ctx, HttpResource{URL: res.URL})
+	require.NoError(t, err)
+	assert.Equal(t, testHttpManifest, string(content))
+
+	_, err = offline.Get(ctx, HttpResource{URL: srv.URL + "/missing.yaml"})
+	require.ErrorContains(t, err, "not cached")
+}
+
+func TestHttpCache_Get_Errors(t *testing.T) {
+	var requests atomic.Int32
+	srv := newTestHttpServer(t, &requests, 0)
+	ctx := context.Background()
+
+	_, err := newTestHttpCache(t.TempDir()).Get(ctx, HttpResource{
+		URL: srv.URL, SHA256: sha256Hex("something else"),
+	})
+	require.ErrorContains(t, err, "sha256 mismatch")
+
+	// client errors are not retried.
+	requests.Store(0)
+	_, err = NewHttpCache(t.TempDir(),
+		WithRetryBackoff(wait.Backoff{Duration: time.Millisecond, Steps: 3}),
+	).Get(ctx, HttpResource{URL: srv.URL})
+	require.ErrorContains(t, err, "401")
+	assert.Equal(t, int32(1), requests.Load())
+
+	// resource headers are sent in addition.
+	_, err = NewHttpCache(t.TempDir()).Get(ctx, HttpResource{
+		URL: srv.URL, Headers: http.Header{"Authorization": {"Bearer token"}},
+	})
+	require.NoError(t, err)
+}
+
+func TestCachingHttpSource_LoadObjects(t *testing.T) {
+	var requests atomic.Int32
+	srv := newTestHttpServer(t, &requests, 0)
+
+	source := newTestHttpCache(t.TempDir()).Source(HttpResource{URL: srv.URL + "/cm.yaml"})
+	objects, err := source.LoadObjects(context.Background(), WithSourceLocations(true))
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "cached", objects[0].GetName())
+
+	loc, ok := GetSourceLocation(&objects[0])
+	require.True(t, ok)
+	assert.Equal(t, srv.URL+"/cm.yaml", loc.Path)
+}
+
+func TestHttpCacheConfig_Default(t *testing.T) {
+	config := HttpCacheConfig{Backoff: wait.Backoff{Steps: 1}}
+	config.Default()
+	assert.Equal(t, wait.Backoff{
+		Duration: 500 * time.Millisecond,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    1,
+		Cap:      10 * time.Second,
+	}, config.Backoff)
+
+	config = HttpCacheConfig{Backoff: wait.Backoff{Duration: time.Second}}
+	config.Default()
+	assert.Equal(t, time.Second, config.Backoff.Duration)
+	assert.Equal(t, 4, config.Backoff.Steps)
+}
+
+func TestCluster_httpSource(t *testing.T) {
+	cluster := newTestCluster(t)
+	cluster.HttpCache = newTestHttpCache(t.TempDir())
+	urls := []string{"https://example.com/cm.yaml"}
+
+	// the cache is opt-in.
+	assert.Equal(t, HttpSource(urls), cluster.httpSource(urls))
+
+	cluster.config.CacheHttpDownloads = true
+	assert.Equal(t, CachingHttpSource{
+		Cache: cluster.HttpCache, Resources: []HttpResource{{URL: urls[0]}},
+	}, cluster.httpSource(urls))
+}
@@ -0,0 +1,294 @@
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"os"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Key of the inventory within the ConfigMap it is persisted to.
+const inventoryConfigMapKey = "inventory.json"
+
+// InventoryEntry identifies an object created through a Cluster.
+type InventoryEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func newInventoryEntry(gvk schema.GroupVersionKind, object client.Object) InventoryEntry {
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	return InventoryEntry{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  object.GetNamespace(),
+		Name:       object.GetName(),
+	}
+}
+
+// Returns an object referencing the entry, e.g. to delete it.
+func (e InventoryEntry) Object() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(e.APIVersion)
+	obj.SetKind(e.Kind)
+	obj.SetNamespace(e.Namespace)
+	obj.SetName(e.Name)
+	return obj
+}
+
+// Inventory records the objects created through a Cluster in creation order,
+// so they can be removed again by Cluster.Teardown.
+// The inventory is optionally persisted, so it can be picked up
+// when a cluster is reused by another process.
+type Inventory struct {
+	store inventoryStore
+
+	mu      sync.Mutex
+	loaded  bool
+	entries []InventoryEntry
+}
+
+// Persists inventory entries.
+type inventoryStore interface {
+	load(ctx context.Context) ([]InventoryEntry, error)
+	save(ctx context.Context, entries []InventoryEntry) error
+}
+
+// Creates a new Inventory persisted to the given store.
+// A nil store keeps the inventory in memory only.
+func newInventory(store inventoryStore) *Inventory {
+	return &Inventory{store: store}
+}
+
+// Returns the recorded entries in creation order.
+func (i *Inventory) Entries(ctx context.Context) ([]InventoryEntry, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	return append([]InventoryEntry(nil), i.entries...), nil
+}
+
+// Records the given entries, ignoring entries that are already recorded.
+// Entries are only persisted by save.
+func (i *Inventory) add(ctx context.Context, entries ...InventoryEntry) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.ensureLoaded(ctx); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if i.indexOf(entry) == -1 {
+			i.entries = append(i.entries, entry)
+		}
+	}
+	return nil
+}
+
+// Removes the given entries from the inventory.
+// Entries are only persisted by save.
+func (i *Inventory) remove(entries ...InventoryEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, entry := range entries {
+		if idx := i.indexOf(entry); idx != -1 {
+			i.entries = append(i.entries[:idx], i.entries[idx+1:]...)
+		}
+	}
+}
+
+// Persists the inventory, if a store is configured.
+func (i *Inventory) save(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.store == nil || !i.loaded {
+		return nil
+	}
+	if err := i.store.save(ctx, i.entries); err != nil {
+		return fmt.Errorf("saving inventory: %w", err)
+	}
+	return nil
+}
+
+func (i *Inventory) ensureLoaded(ctx context.Context) error {
+	if i.loaded {
+		return nil
+	}
+	if i.store != nil {
+		entries, err := i.store.load(ctx)
+		if err != nil {
+			return fmt.Errorf("loading inventory: %w", err)
+		}
+		i.entries = entries
+	}
+	i.loaded = true
+	return nil
+}
+
+func (i *Inventory) indexOf(entry InventoryEntry) int {
+	for idx := range i.entries {
+		if i.entries[idx] == entry {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Persists the inventory in a ConfigMap.
+type configMapInventoryStore struct {
+	client client.Client
+	key    client.ObjectKey
+}
+
+func (s *configMapInventoryStore) load(ctx context.Context) ([]InventoryEntry, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, s.key, cm); errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting ConfigMap %s: %w", s.key, err)
+	}
+
+	var entries []InventoryEntry
+	if err := json.Unmarshal([]byte(cm.Data[inventoryConfigMapKey]), &entries); err != nil {
+		return nil, fmt.Errorf("unmarshalling ConfigMap %s: %w", s.key, err)
+	}
+	return entries, nil
+}
+
+func (s *configMapInventoryStore) save(ctx context.Context, entries []InventoryEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: s.key.Name, Namespace: s.key.Namespace},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, s.client, cm, func() error {
+		cm.Data = map[string]string{inventoryConfigMapKey: string(data)}
+		return nil
+	})
+	if len(entries) == 0 && errors.IsNotFound(err) {
+		// The Namespace of the ConfigMap has been torn down with the recorded objects,
+		// so there is nothing left to persist.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("updating ConfigMap %s: %w", s.key, err)
+	}
+	return nil
+}
+
+// Persists the inventory in a file.
+type fileInventoryStore struct {
+	path string
+}
+
+func (s *fileInventoryStore) load(context.Context) ([]InventoryEntry, error) {
+	data, err := os.ReadFile(s.path)
+	if goerrors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []InventoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unmarshalling %s: %w", s.path, err)
+	}
+	return entries, nil
+}
+
+func (s *fileInventoryStore) save(_ context.Context, entries []InventoryEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+// Records the given object in the inventory of the cluster.
+func (c *Cluster) recordInInventory(ctx context.Context, object client.Object) error {
+	if c.Inventory == nil {
+		return nil
+	}
+
+	gvk, err := apiutil.GVKForObject(object, c.Scheme)
+	if err != nil {
+		return err
+	}
+	return c.Inventory.add(ctx, newInventoryEntry(gvk, object))
+}
+
+// Persists the inventory of the cluster and adds errors to err.
+func (c *Cluster) saveInventory(ctx context.Context, err *error) {
+	if c.Inventory == nil {
+		return
+	}
+	if saveErr := c.Inventory.save(ctx); saveErr != nil {
+		*err = goerrors.Join(*err, saveErr)
+	}
+}
+
+// Deletes all objects recorded in the inventory and waits for them to be gone.
+// Objects are deleted in reverse install wave order,
+// e.g. custom resources before their CRDs and Namespaces last.
+// Teardown stops at the first wave failing to be deleted.
+func (c *Cluster) Teardown(ctx context.Context, opts ...WaitOption) (err error) {
+	if c.Inventory == nil {
+		return nil
+	}
+	entries, err := c.Inventory.Entries(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.saveInventory(ctx, &err)
+
+	objects := make([]unstructured.Unstructured, len(entries))
+	for i, entry := range entries {
+		objects[i] = *entry.Object()
+	}
+	waves := GroupObjectsByInstallWave(objects)
+	for i := len(waves) - 1; i >= 0; i-- {
+		wave := waves[i]
+		// Delete everything first, so objects of a wave are removed in parallel.
+		for j := len(wave) - 1; j >= 0; j-- {
+			if err := c.delete(ctx, &wave[j], metav1.DeletePropagationBackground); err != nil {
+				return fmt.Errorf("tearing down: %w", err)
+			}
+		}
+
+		var errs []error
+		for j := len(wave) - 1; j >= 0; j-- {
+			obj := &wave[j]
+			entry := newInventoryEntry(obj.GroupVersionKind(), obj)
+			if err := c.waitToBeGone(ctx, obj, opts...); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			c.Inventory.remove(entry)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("tearing down: %w", goerrors.Join(errs...))
+		}
+	}
+	return nil
+}
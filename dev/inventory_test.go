@@ -0,0 +1,129 @@
+package dev
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestCluster_Teardown(t *testing.T) {
+	ctx := context.Background()
+
+	var deleted []string
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Delete: func(
+			ctx context.Context, c client.WithWatch,
+			obj client.Object, opts ...client.DeleteOption,
+		) error {
+			deleted = append(deleted, obj.GetName())
+			return c.Delete(ctx, obj, opts...)
+		},
+	})
+	inventoryKey := client.ObjectKey{Name: "inventory", Namespace: "default"}
+	cluster.Inventory = newInventory(&configMapInventoryStore{
+		client: cluster.CtrlClient, key: inventoryKey,
+	})
+
+	objects := []unstructured.Unstructured{
+		newTestObject("v1", "Namespace", "test"),
+		newTestObject("v1", "ConfigMap", "cm"),
+		*newTestCR(""),
+	}
+	objects[1].SetNamespace("test")
+	require.NoError(t, cluster.createObjectsFromSource(ctx, "test", objects))
+
+	// a new inventory picks up the persisted state.
+	inventory := newInventory(&configMapInventoryStore{
+		client: cluster.CtrlClient, key: inventoryKey,
+	})
+	entries, err := inventory.Entries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []InventoryEntry{
+		{APIVersion: "v1", Kind: "Namespace", Name: "test"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "cm"},
+		{APIVersion: "test.devkube.io/v1", Kind: "Cheese", Namespace: "test", Name: "gouda"},
+	}, entries)
+
+	cluster.Inventory = inventory
+	require.NoError(t, cluster.Teardown(ctx))
+	assert.Equal(t, []string{"gouda", "cm", "test"}, deleted)
+
+	for _, obj := range objects {
+		err := cluster.CtrlClient.Get(ctx, client.ObjectKeyFromObject(&obj), obj.DeepCopy())
+		assert.True(t, errors.IsNotFound(err), "%s should be gone: %v", obj.GetName(), err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cluster.CtrlClient.Get(ctx, inventoryKey, cm))
+	assert.Equal(t, "[]", cm.Data[inventoryConfigMapKey])
+}
+
+func TestCluster_Teardown_InventoryNamespace(t *testing.T) {
+	ctx := context.Background()
+	inventoryKey := client.ObjectKey{Name: "inventory", Namespace: "test"}
+	// the fake client does not delete the contents of Namespaces.
+	inventoryNamespaceGone := func(ctx context.Context, c client.WithWatch, obj client.Object) error {
+		if client.ObjectKeyFromObject(obj) != inventoryKey {
+			return nil
+		}
+		err := c.Get(ctx, client.ObjectKey{Name: "test"}, &corev1.Namespace{})
+		if errors.IsNotFound(err) {
+			return errors.NewNotFound(corev1.Resource("namespaces"), "test")
+		}
+		return err
+	}
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Create: func(
+			ctx context.Context, c client.WithWatch,
+			obj client.Object, opts ...client.CreateOption,
+		) error {
+			if err := inventoryNamespaceGone(ctx, c, obj); err != nil {
+				return err
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(
+			ctx context.Context, c client.WithWatch,
+			obj client.Object, opts ...client.UpdateOption,
+		) error {
+			if err := inventoryNamespaceGone(ctx, c, obj); err != nil {
+				return err
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	})
+	cluster.Inventory = newInventory(&configMapInventoryStore{
+		client: cluster.CtrlClient, key: inventoryKey,
+	})
+
+	require.NoError(t, cluster.createObjectsFromSource(ctx, "test", []unstructured.Unstructured{
+		newTestObject("v1", "Namespace", "test"),
+	}))
+	// the empty inventory can't be saved into the deleted Namespace.
+	require.NoError(t, cluster.Teardown(ctx))
+}
+
+func Test_fileInventoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := &fileInventoryStore{path: filepath.Join(t.TempDir(), "inventory.json")}
+
+	entries, err := store.load(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	stored := []InventoryEntry{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "cm"},
+	}
+	require.NoError(t, store.save(ctx, stored))
+	entries, err = store.load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, stored, entries)
+}
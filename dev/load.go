@@ -2,95 +2,393 @@ package dev
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	"net/http"
 	"os"
 	"path"
-	"sort"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
 	"sigs.k8s.io/yaml"
 )
 
-// Loads kubernets objects from all .yaml files in the given folder.
-// Does not recurse into subfolders.
+// Configures how manifests are rendered before they are parsed.
+type LoadConfig struct {
+	// Render manifests as Go text/template with these values, when set.
+	// Sprig functions are available and referencing missing values is an error.
+	TemplateValues map[string]interface{}
+	// Substitute ${VAR} references in manifests with these values, when set.
+	// Referencing missing values is an error, $${VAR} results in a literal ${VAR}.
+	// Substitution happens after templates have been rendered.
+	EnvSubst map[string]string
+	// Recurse into subfolders when loading objects from folders.
+	Recursive bool
+	// Transform loaded objects in order.
+	// Transformers run once on all objects returned by a loader.
+	Transformers []Transformer
+	// Record where each object has been loaded from in the SourceAnnotation.
+	// Callers creating these objects themselves have to remove the annotation,
+	// see RemoveSourceLocation. Cluster.CreateAndWait enables and strips it itself.
+	SourceLocations bool
+}
+
+type LoadOption interface {
+	ApplyToLoadConfig(c *LoadConfig)
+}
+
+// Loads kubernets objects from all .yaml, .yml and .json files in the given folder.
+// Only recurses into subfolders when configured, see LoadConfig.Recursive.
 // Preserves lexical file order.
-func LoadKubernetesObjectsFromFolder(folderPath string) ([]unstructured.Unstructured, error) {
-	folder, err := os.Open(folderPath)
+func LoadKubernetesObjectsFromFolder(
+	folderPath string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	objects, err := loadKubernetesObjectsFromFSFolder(os.DirFS(folderPath), ".", folderPath, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("open %q: %w", folderPath, err)
+		return nil, err
 	}
-	defer folder.Close()
+	return transformLoadedObjects(objects, opts...)
+}
 
-	files, err := folder.Readdir(-1)
+// Loads kubernets objects from all .yaml, .yml and .json files in the given folder of fsys,
+// e.g. an embed.FS shipping manifests within a binary.
+// Only recurses into subfolders when configured, see LoadConfig.Recursive.
+// Preserves lexical file order.
+func LoadKubernetesObjectsFromFSFolder(
+	fsys fs.FS, folderPath string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	objects, err := loadKubernetesObjectsFromFSFolder(fsys, folderPath, folderPath, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("read directory: %w", err)
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
+}
+
+// File extensions of manifests loaded from folders.
+var manifestExtensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// Loads kubernetes objects from the given folder of fsys.
+// name is the path of the folder used in errors.
+func loadKubernetesObjectsFromFSFolder(
+	fsys fs.FS, folderPath, name string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var config LoadConfig
+	for _, opt := range opts {
+		opt.ApplyToLoadConfig(&config)
 	}
-	sort.Sort(fileInfosByName(files))
 
 	var objects []unstructured.Unstructured
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	// WalkDir visits entries in lexical order.
+	err := fs.WalkDir(fsys, folderPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("read directory %q: %w", path.Join(name, relPath(folderPath, filePath)), err)
+		}
+		if entry.IsDir() {
+			if filePath != folderPath && !config.Recursive {
+				return fs.SkipDir
+			}
+			return nil
 		}
 
-		if path.Ext(file.Name()) != ".yaml" {
-			continue
+		if !manifestExtensions[path.Ext(entry.Name())] {
+			return nil
 		}
 
-		objs, err := LoadKubernetesObjectsFromFile(path.Join(folderPath, file.Name()))
+		fileName := path.Join(name, relPath(folderPath, filePath))
+		objs, err := loadKubernetesObjectsFromFSFile(fsys, filePath, fileName, opts...)
 		if err != nil {
-			return nil, fmt.Errorf("loading kubernetes objects from file %q: %w", file, err)
+			return fmt.Errorf("loading kubernetes objects from file %q: %w", fileName, err)
 		}
 		objects = append(objects, objs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Returns filePath relative to the folder containing it.
+func relPath(folderPath, filePath string) string {
+	if folderPath == "." {
+		return filePath
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(filePath, folderPath), "/")
+}
+
+// Builds the kustomization in the given directory in-process
+// and returns the resulting objects.
+// Only transformers of the load options apply, kustomize does its own rendering.
+func LoadKubernetesObjectsFromKustomization(
+	dir string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	objects, err := buildKustomization(filesys.MakeFsOnDisk(), dir, dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
+}
+
+// Builds the kustomization in the given directory of fsys in-process
+// and returns the resulting objects.
+// All bases and resources need to be contained in fsys.
+// kustomize can't read from fs.FS, so all of fsys is copied into memory first,
+// regardless of dir. For large filesystems, pass a sub-tree containing only
+// the kustomization and its bases, e.g. via fs.Sub.
+// Only transformers of the load options apply, kustomize does its own rendering.
+func LoadKubernetesObjectsFromFSKustomization(
+	fsys fs.FS, dir string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	// Bases may live anywhere within fsys, e.g. "../../base",
+	// so the whole filesystem is copied.
+	memFS := filesys.MakeFsInMemory()
+	if err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return memFS.MkdirAll(path.Join("/", p))
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return memFS.WriteFile(path.Join("/", p), content)
+	}); err != nil {
+		return nil, fmt.Errorf("copying kustomization %q: %w", dir, err)
+	}
+
+	objects, err := buildKustomization(memFS, path.Join("/", dir), dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
+}
+
+// Builds the kustomization in dir of fSys.
+// name is the path of the kustomization used in errors.
+func buildKustomization(
+	fSys filesys.FileSystem, dir, name string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var config LoadConfig
+	for _, opt := range opts {
+		opt.ApplyToLoadConfig(&config)
+	}
+
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := kustomizer.Run(fSys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("building kustomization %q: %w", name, err)
+	}
+
+	var objects []unstructured.Unstructured
+	for i, res := range resMap.Resources() {
+		// Map() returns numbers as int, which Unstructured can't deep copy,
+		// so objects are decoded from JSON like the loaders do.
+		resJSON, err := res.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("converting %s: %w", res.CurId(), err)
+		}
+		u := unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(resJSON); err != nil {
+			return nil, fmt.Errorf("converting %s: %w", res.CurId(), err)
+		}
+		if config.SourceLocations {
+			// kustomize does not track the files objects originate from.
+			setSourceLocation(&u, SourceLocation{Path: name, Document: i})
+		}
+		objects = append(objects, u)
 	}
 	return objects, nil
 }
 
 // Loads kubernetes objects from the given file.
-func LoadKubernetesObjectsFromFile(filePath string) ([]unstructured.Unstructured, error) {
+func LoadKubernetesObjectsFromFile(
+	filePath string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
 	fileYaml, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("reading %s: %w", filePath, err)
 	}
 
-	return LoadKubernetesObjectsFromBytes(fileYaml)
+	objects, err := loadKubernetesObjectsFromBytes(filePath, fileYaml, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
+}
+
+// Loads kubernetes objects from the given file of fsys,
+// e.g. an embed.FS shipping manifests within a binary.
+func LoadKubernetesObjectsFromFSFile(
+	fsys fs.FS, filePath string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	objects, err := loadKubernetesObjectsFromFSFile(fsys, filePath, filePath, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
+}
+
+// Loads kubernetes objects from the given file of fsys.
+// name is the path of the file used in errors.
+func loadKubernetesObjectsFromFSFile(
+	fsys fs.FS, filePath, name string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	fileYaml, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	return loadKubernetesObjectsFromBytes(name, fileYaml, opts...)
+}
+
+// Loads kubernetes objects from the given http url.
+func LoadKubernetesObjectsFromHttp(
+	ctx context.Context, url string, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getting %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("getting %q: unexpected status %s", url, resp.Status)
+	}
+
+	var content bytes.Buffer
+	if _, err := io.Copy(&content, resp.Body); err != nil {
+		return nil, fmt.Errorf("reading response %q: %w", url, err)
+	}
+
+	objects, err := loadKubernetesObjectsFromBytes(url, content.Bytes(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
 }
 
 // Loads kubernetes objects from given bytes.
-// A single file may contain multiple objects separated by "---\n".
-func LoadKubernetesObjectsFromBytes(fileYaml []byte) ([]unstructured.Unstructured, error) {
-	// Trim empty starting and ending objects
-	fileYaml = bytes.Trim(fileYaml, "-\n")
+// A single file may contain a stream of multiple YAML documents separated by "---" or JSON objects.
+// Empty documents are skipped and v1 List objects are expanded into their items.
+// Where each object has been loaded from is recorded in the SourceAnnotation, when enabled,
+// see LoadConfig.SourceLocations.
+func LoadKubernetesObjectsFromBytes(
+	fileYaml []byte, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	objects, err := loadKubernetesObjectsFromBytes("manifest", fileYaml, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return transformLoadedObjects(objects, opts...)
+}
+
+// Renders and loads kubernetes objects from given bytes.
+// name identifies the source of the bytes in errors and source locations.
+func loadKubernetesObjectsFromBytes(
+	name string, fileYaml []byte, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var config LoadConfig
+	for _, opt := range opts {
+		opt.ApplyToLoadConfig(&config)
+	}
+	fileYaml, err := renderManifest(name, fileYaml, config)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := splitManifest(fileYaml)
+	if err != nil {
+		return nil, err
+	}
 
 	var objects []unstructured.Unstructured
-	// Split for every included yaml document.
-	for i, yamlDocument := range bytes.Split(fileYaml, []byte("---\n")) {
-		obj := unstructured.Unstructured{}
-		if err := yaml.Unmarshal(yamlDocument, &obj); err != nil {
+	for _, doc := range docs {
+		obj, ok, err := decodeObject(doc.data)
+		if err != nil {
 			return nil, fmt.Errorf(
-				"unmarshalling yaml document at index %d: %w", i, err)
+				"unmarshalling yaml document at index %d, line %d: %w", doc.index, doc.line, err)
+		}
+		if !ok {
+			// comment-only document.
+			continue
 		}
 
-		objects = append(objects, obj)
+		loc := SourceLocation{Path: name, Document: doc.index, Line: doc.line}
+		if !isListKind(obj) {
+			if config.SourceLocations {
+				setSourceLocation(obj, loc)
+			}
+			objects = append(objects, *obj)
+			continue
+		}
+		list, err := obj.ToList()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"expanding list in yaml document at index %d, line %d: %w", doc.index, doc.line, err)
+		}
+		if config.SourceLocations {
+			for i := range list.Items {
+				setSourceLocation(&list.Items[i], loc)
+			}
+		}
+		objects = append(objects, list.Items...)
 	}
 
 	return objects, nil
 }
 
-// Sorts fs.FileInfo objects by basename.
-type fileInfosByName []fs.FileInfo
-
-func (x fileInfosByName) Len() int { return len(x) }
+// Decodes a YAML or JSON document into an object.
+// Numbers are decoded as int64 where possible, like the API machinery does.
+// Returns false for documents without content.
+func decodeObject(doc []byte) (*unstructured.Unstructured, bool, error) {
+	docJSON, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes.Equal(bytes.TrimSpace(docJSON), []byte("null")) {
+		return nil, false, nil
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(docJSON); err != nil {
+		return nil, false, err
+	}
+	return obj, true, nil
+}
 
-func (x fileInfosByName) Less(i, j int) bool {
-	iName := path.Base(x[i].Name())
-	jName := path.Base(x[j].Name())
-	return iName < jName
+// Runs the transformers of the given options on the loaded objects.
+func transformLoadedObjects(
+	objects []unstructured.Unstructured, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var config LoadConfig
+	for _, opt := range opts {
+		opt.ApplyToLoadConfig(&config)
+	}
+	objects, err := Transformers(config.Transformers).Transform(objects)
+	if err != nil {
+		return nil, fmt.Errorf("transforming objects: %w", err)
+	}
+	return objects, nil
 }
 
-func (x fileInfosByName) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+// Returns true for v1 List objects, wrapping other objects in their items.
+func isListKind(obj *unstructured.Unstructured) bool {
+	return obj.GetAPIVersion() == "v1" && obj.GetKind() == "List"
+}
 
 // LoadAndConvertIntoObject loads one Kubernetes object from a file into the out object.
 // It uses the `Convert` method of `scheme` under the hood, so it does any conversion
@@ -102,6 +400,9 @@ func LoadAndConvertIntoObject(scheme *runtime.Scheme, filePath string, out inter
 	if err != nil {
 		return fmt.Errorf("loading object from file: %w", err)
 	}
+	if len(objs) == 0 {
+		return fmt.Errorf("no object found in %s", filePath)
+	}
 	if err := scheme.Convert(&objs[0], out, nil); err != nil {
 		return fmt.Errorf("converting: %w", err)
 	}
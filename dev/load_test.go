@@ -1,14 +1,26 @@
 package dev
 
 import (
+	"context"
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"testing"
 )
 
+//go:embed testdata
+var testdataFS embed.FS
+
 func TestLoadAndConvertIntoObject(t *testing.T) {
 	testScheme := runtime.NewScheme()
 	if err := appsv1.AddToScheme(testScheme); err != nil {
@@ -34,6 +46,24 @@ func TestLoadAndConvertIntoObject(t *testing.T) {
 		deployment.Spec.Template.Spec.Containers)
 }
 
+func TestLoadAndConvertIntoObject_NoObject(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	require.NoError(t, appsv1.AddToScheme(testScheme))
+
+	for name, content := range map[string]string{
+		"empty":        "",
+		"comment only": "# only a comment\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "deployment.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+
+			err := LoadAndConvertIntoObject(testScheme, file, &appsv1.Deployment{})
+			require.ErrorContains(t, err, "no object found in "+file)
+		})
+	}
+}
+
 func TestLoadAndUnmarshalIntoObject(t *testing.T) {
 	deployment := &appsv1.Deployment{}
 	err := LoadAndUnmarshalIntoObject("testdata/deployment.yaml", deployment)
@@ -53,3 +83,184 @@ func TestLoadAndUnmarshalIntoObject(t *testing.T) {
 	assert.Equal(t, expectedContainers,
 		deployment.Spec.Template.Spec.Containers)
 }
+
+func TestLoadKubernetesObjectsFromKustomization(t *testing.T) {
+	objects, err := LoadKubernetesObjectsFromKustomization("testdata/kustomize/overlay")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	assert.Equal(t, "overlay-test-config", objects[0].GetName())
+	assert.Equal(t, "test-namespace", objects[0].GetNamespace())
+	assert.Equal(t, map[string]interface{}{"key": "overlay"}, objects[0].Object["data"])
+}
+
+func TestLoadKubernetesObjectsFromHttp(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+	ctx := context.Background()
+
+	objects, err := LoadKubernetesObjectsFromHttp(ctx, srv.URL+"/deployment.yaml")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "test-deployment", objects[0].GetName())
+
+	_, err = LoadKubernetesObjectsFromHttp(ctx, srv.URL+"/missing.yaml")
+	require.ErrorContains(t, err, "404")
+}
+
+func TestLoadKubernetesObjectsFromFSFolder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifests/b.yaml":          {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n")},
+		"manifests/a.yaml":          {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n")},
+		"manifests/README.md":       {Data: []byte("not a manifest")},
+		"manifests/sub/c.yaml":      {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\n")},
+		"manifests/broken/bad.yaml": {Data: []byte("{")},
+	}
+
+	objects, err := LoadKubernetesObjectsFromFSFolder(fsys, "manifests")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, objectNames(objects))
+
+	_, err = LoadKubernetesObjectsFromFSFolder(fsys, "manifests/broken")
+	require.ErrorContains(t, err, "manifests/broken/bad.yaml")
+
+	_, err = LoadKubernetesObjectsFromFSFolder(fsys, "missing")
+	require.Error(t, err)
+}
+
+func TestLoadKubernetesObjectsFromFSFile(t *testing.T) {
+	objects, err := LoadKubernetesObjectsFromFSFile(testdataFS, "testdata/deployment.yaml")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "test-deployment", objects[0].GetName())
+}
+
+func TestLoadKubernetesObjectsFromFSKustomization(t *testing.T) {
+	objects, err := LoadKubernetesObjectsFromFSKustomization(
+		testdataFS, "testdata/kustomize/overlay")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "overlay-test-config", objects[0].GetName())
+	assert.Equal(t, map[string]interface{}{"key": "overlay"}, objects[0].Object["data"])
+}
+
+func TestLoadKubernetesObjectsFromBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []string
+	}{
+		{
+			name:     "single",
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+			expected: []string{"a"},
+		},
+		{
+			name: "separators",
+			manifest: "--- \napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n" +
+				"--- # second\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n---\n",
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "crlf",
+			manifest: "apiVersion: v1\r\nkind: ConfigMap\r\nmetadata:\r\n  name: a\r\n" +
+				"---\r\napiVersion: v1\r\nkind: ConfigMap\r\nmetadata:\r\n  name: b\r\n",
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "empty and comment-only documents",
+			manifest: "---\n---\n# just a comment\n---\n" +
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n\n",
+			expected: []string{"a"},
+		},
+		{
+			name: "list",
+			manifest: "apiVersion: v1\nkind: List\nitems:\n" +
+				"- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: a\n" +
+				"- apiVersion: v1\n  kind: Secret\n  metadata:\n    name: b\n" +
+				"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\n",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name: "json",
+			manifest: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a"}}
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "b"}}`,
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "empty",
+			manifest: "",
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects, err := LoadKubernetesObjectsFromBytes([]byte(test.manifest))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, objectNames(objects))
+		})
+	}
+
+	_, err := LoadKubernetesObjectsFromBytes([]byte(
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n{"))
+	require.ErrorContains(t, err, "index 1")
+}
+
+func TestLoadKubernetesObjectsFromBytes_Int64(t *testing.T) {
+	for name, manifest := range map[string]string{
+		"yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: a\nspec:\n  replicas: 3\n",
+		"json": `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "a"}, "spec": {"replicas": 3}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			objects, err := LoadKubernetesObjectsFromBytes([]byte(manifest))
+			require.NoError(t, err)
+			require.Len(t, objects, 1)
+
+			replicas, found, err := unstructured.NestedInt64(objects[0].Object, "spec", "replicas")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, int64(3), replicas)
+		})
+	}
+}
+
+func TestLoadKubernetesObjectsFromFolder_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":       "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+		"b.yml":        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n",
+		"c.json":       `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "c"}}`,
+		"d.txt":        "ignored",
+		"sub/e.yaml":   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: e\n",
+		"sub/x/f.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: f\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	objects, err := LoadKubernetesObjectsFromFolder(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, objectNames(objects))
+
+	objects, err = LoadKubernetesObjectsFromFolder(dir, WithRecursive(true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "e", "f"}, objectNames(objects))
+}
+
+func TestLoadKubernetesObjectsFromFSKustomization_Int64(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kustomization.yaml": {Data: []byte("resources:\n- deployment.yaml\n")},
+		"deployment.yaml": {Data: []byte(
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: a\nspec:\n  replicas: 3\n")},
+	}
+	objects, err := LoadKubernetesObjectsFromFSKustomization(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	replicas, found, err := unstructured.NestedInt64(objects[0].DeepCopy().Object, "spec", "replicas")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(3), replicas)
+}
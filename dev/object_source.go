@@ -0,0 +1,207 @@
+package dev
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ObjectSource provides objects to be created by Cluster.CreateAndWait.
+// Implement it to create objects from other places than the ones provided here.
+type ObjectSource interface {
+	// Returns the objects to create in order.
+	// Load options configure how manifests are rendered,
+	// sources not loading manifests may ignore them.
+	LoadObjects(ctx context.Context, opts ...LoadOption) ([]unstructured.Unstructured, error)
+	// Describes the source in errors.
+	String() string
+}
+
+// Combines multiple sources, objects are loaded from each source in order.
+type ObjectSources []ObjectSource
+
+func (s ObjectSources) LoadObjects(
+	ctx context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, source := range s {
+		objs, err := source.LoadObjects(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s ObjectSources) String() string {
+	names := make([]string, len(s))
+	for i, source := range s {
+		names[i] = source.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Loads objects from the given files, see LoadKubernetesObjectsFromFile.
+type FilesSource []string
+
+func (s FilesSource) LoadObjects(
+	_ context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, file := range s {
+		objs, err := LoadKubernetesObjectsFromFile(file, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from file %q: %w", file, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s FilesSource) String() string { return "files" }
+
+// Loads objects from the given folders, see LoadKubernetesObjectsFromFolder.
+type FoldersSource []string
+
+func (s FoldersSource) LoadObjects(
+	_ context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, folder := range s {
+		objs, err := LoadKubernetesObjectsFromFolder(folder, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from folder %q: %w", folder, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s FoldersSource) String() string { return "folders" }
+
+// Loads objects from the given http urls, see LoadKubernetesObjectsFromHttp.
+type HttpSource []string
+
+func (s HttpSource) LoadObjects(
+	ctx context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, url := range s {
+		objs, err := LoadKubernetesObjectsFromHttp(ctx, url, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from %q: %w", url, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s HttpSource) String() string { return "http" }
+
+// Builds objects from the given kustomization directories,
+// see LoadKubernetesObjectsFromKustomization.
+// Only transformers of the load options apply, kustomize does its own rendering.
+type KustomizationsSource []string
+
+func (s KustomizationsSource) LoadObjects(
+	_ context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, dir := range s {
+		objs, err := LoadKubernetesObjectsFromKustomization(dir, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from kustomization %q: %w", dir, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s KustomizationsSource) String() string { return "kustomizations" }
+
+// Loads objects from files, folders and kustomizations within FS,
+// e.g. an embed.FS shipping manifests within a binary.
+// Files are loaded first, followed by folders and kustomizations.
+// Only transformers of the load options apply to kustomizations.
+type FSSource struct {
+	FS             fs.FS
+	Files          []string
+	Folders        []string
+	Kustomizations []string
+}
+
+func (s FSSource) LoadObjects(
+	_ context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, file := range s.Files {
+		objs, err := LoadKubernetesObjectsFromFSFile(s.FS, file, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from file %q: %w", file, err)
+		}
+		objects = append(objects, objs...)
+	}
+	for _, folder := range s.Folders {
+		objs, err := LoadKubernetesObjectsFromFSFolder(s.FS, folder, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from folder %q: %w", folder, err)
+		}
+		objects = append(objects, objs...)
+	}
+	for _, dir := range s.Kustomizations {
+		objs, err := LoadKubernetesObjectsFromFSKustomization(s.FS, dir, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("loading objects from kustomization %q: %w", dir, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func (s FSSource) String() string { return "fs" }
+
+// Provides the given in-memory objects.
+// Objects need to carry apiVersion and kind, unless they are built-in typed objects.
+// Load options are ignored.
+type ObjectsSource []client.Object
+
+func (s ObjectsSource) LoadObjects(
+	context.Context, ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	objects := make([]unstructured.Unstructured, len(s))
+	for i, obj := range s {
+		if err := copyToUnstructured(obj, &objects[i]); err != nil {
+			return nil, err
+		}
+		if !objects[i].GroupVersionKind().Empty() {
+			continue
+		}
+		gvk, err := apiutil.GVKForObject(obj, builtinScheme)
+		if err != nil {
+			return nil, fmt.Errorf("object %s: %w", client.ObjectKeyFromObject(obj), err)
+		}
+		objects[i].SetGroupVersionKind(gvk)
+	}
+	return objects, nil
+}
+
+func (s ObjectsSource) String() string { return "objects" }
+
+// Generates objects, e.g. from code.
+type ObjectSourceFunc func(
+	ctx context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error)
+
+func (fn ObjectSourceFunc) LoadObjects(
+	ctx context.Context, opts ...LoadOption,
+) ([]unstructured.Unstructured, error) {
+	return fn(ctx, opts...)
+}
+
+func (fn ObjectSourceFunc) String() string { return "func" }
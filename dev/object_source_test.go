@@ -0,0 +1,72 @@
+package dev
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestObjectSources_LoadObjects(t *testing.T) {
+	var funcOpts []LoadOption
+	source := ObjectSources{
+		FilesSource{"testdata/deployment.yaml"},
+		ObjectsSource{&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "typed", Namespace: "default"},
+		}},
+		ObjectSourceFunc(func(
+			_ context.Context, opts ...LoadOption,
+		) ([]unstructured.Unstructured, error) {
+			funcOpts = opts
+			return []unstructured.Unstructured{*newTestConfigMap("generated", "v")}, nil
+		}),
+	}
+	assert.Equal(t, "files, objects, func", source.String())
+
+	objects, err := source.LoadObjects(context.Background(), WithRecursive(true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-deployment", "typed", "generated"}, objectNames(objects))
+	assert.Equal(t, "ConfigMap", objects[1].GetKind())
+	assert.Equal(t, "v1", objects[1].GetAPIVersion())
+	assert.Equal(t, []LoadOption{WithRecursive(true)}, funcOpts)
+
+	_, err = FoldersSource{"missing"}.LoadObjects(context.Background())
+	require.ErrorContains(t, err, `loading objects from folder "missing"`)
+}
+
+func TestCluster_CreateAndWait(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+
+	err := cluster.CreateAndWaitWithOptions(ctx, []ObjectSource{
+		ObjectsSource{&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "typed", Namespace: "default"},
+		}},
+		FilesSource{"testdata/deployment.yaml"},
+	}, WithTimeout(50*time.Millisecond))
+	// wait options are passed on,
+	// the Deployment never becomes available with the fake client.
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+	assert.Contains(t, err.Error(), "objects, files")
+
+	require.NoError(t, cluster.CtrlClient.Get(ctx,
+		client.ObjectKey{Namespace: "default", Name: "typed"}, &corev1.ConfigMap{}))
+
+	// sources are created in order.
+	require.NoError(t, cluster.CreateAndWait(ctx,
+		ObjectsSource{newTestConfigMap("a", "a")},
+		ObjectsSource{newTestConfigMap("b", "b")},
+	))
+	for _, name := range []string{"a", "b"} {
+		assert.NoError(t, cluster.CtrlClient.Get(ctx,
+			client.ObjectKey{Namespace: "test", Name: name}, &corev1.ConfigMap{}))
+	}
+}
@@ -2,9 +2,12 @@ package dev
 
 import (
 	"io"
+	"net/http"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 )
 
@@ -14,12 +17,221 @@ func (i WithInterval) ApplyToWaiterConfig(c *WaiterConfig) {
 	c.Interval = time.Duration(i)
 }
 
+func (i WithInterval) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, i)
+}
+
+// Exponential backoff with jitter between polls, replacing the fixed interval.
+// e.g. WithBackoff{Duration: 100 * time.Millisecond, Factor: 2, Jitter: 0.1, Cap: 10 * time.Second}.
+type WithBackoff wait.Backoff
+
+func (b WithBackoff) ApplyToWaiterConfig(c *WaiterConfig) {
+	backoff := wait.Backoff(b)
+	c.Backoff = &backoff
+}
+
+func (b WithBackoff) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, b)
+}
+
 type WithTimeout time.Duration
 
 func (t WithTimeout) ApplyToWaiterConfig(c *WaiterConfig) {
 	c.Timeout = time.Duration(t)
 }
 
+func (t WithTimeout) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, t)
+}
+
+// Watch objects for changes instead of polling them.
+type WithWatch bool
+
+func (w WithWatch) ApplyToWaiterConfig(c *WaiterConfig) {
+	c.Watch = bool(w)
+}
+
+func (w WithWatch) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, w)
+}
+
+// Sort objects into install waves before creating them, see SortObjectsByInstallWave.
+type WithInstallOrder bool
+
+func (o WithInstallOrder) ApplyToCreateConfig(c *CreateConfig) {
+	c.InstallOrder = bool(o)
+}
+
+// Create objects in install waves, concurrently within each wave.
+// See CreateConfig.InstallWaves.
+type WithInstallWaves bool
+
+func (w WithInstallWaves) ApplyToCreateConfig(c *CreateConfig) {
+	c.InstallWaves = bool(w)
+}
+
+// Number of objects created and waited on concurrently within an install wave.
+type WithWorkers int
+
+func (n WithWorkers) ApplyToCreateConfig(c *CreateConfig) {
+	c.Workers = int(n)
+}
+
+// Render manifests as Go text/template with the given values before parsing them.
+// See LoadConfig.TemplateValues.
+type WithTemplateValues map[string]interface{}
+
+func (v WithTemplateValues) ApplyToLoadConfig(c *LoadConfig) {
+	c.TemplateValues = v
+}
+
+func (v WithTemplateValues) ApplyToCreateConfig(c *CreateConfig) {
+	c.LoadOptions = append(c.LoadOptions, v)
+}
+
+// Substitute ${VAR} references in manifests with the given values before parsing them.
+// See LoadConfig.EnvSubst.
+type WithEnvSubst map[string]string
+
+func (v WithEnvSubst) ApplyToLoadConfig(c *LoadConfig) {
+	c.EnvSubst = v
+}
+
+func (v WithEnvSubst) ApplyToCreateConfig(c *CreateConfig) {
+	c.LoadOptions = append(c.LoadOptions, v)
+}
+
+// Recurse into subfolders when loading objects from folders.
+type WithRecursive bool
+
+func (r WithRecursive) ApplyToLoadConfig(c *LoadConfig) {
+	c.Recursive = bool(r)
+}
+
+func (r WithRecursive) ApplyToCreateConfig(c *CreateConfig) {
+	c.LoadOptions = append(c.LoadOptions, r)
+}
+
+// Record where loaded objects come from in the SourceAnnotation.
+// See LoadConfig.SourceLocations.
+type WithSourceLocations bool
+
+func (l WithSourceLocations) ApplyToLoadConfig(c *LoadConfig) {
+	c.SourceLocations = bool(l)
+}
+
+func (l WithSourceLocations) ApplyToCreateConfig(c *CreateConfig) {
+	c.LoadOptions = append(c.LoadOptions, l)
+}
+
+// Transform loaded objects before they are created, see Transformer.
+// With the CreateAndWait* methods, transformers run once on the objects of all sources.
+type WithTransformers []Transformer
+
+func (t WithTransformers) ApplyToLoadConfig(c *LoadConfig) {
+	c.Transformers = append(c.Transformers, t...)
+}
+
+func (t WithTransformers) ApplyToCreateConfig(c *CreateConfig) {
+	c.Transformers = append(c.Transformers, t...)
+}
+
+// Patch loaded objects before they are created, see PatchTransformer.
+type WithPatches []Patch
+
+func (p WithPatches) ApplyToLoadConfig(c *LoadConfig) {
+	c.Transformers = append(c.Transformers, PatchTransformer(p))
+}
+
+func (p WithPatches) ApplyToCreateConfig(c *CreateConfig) {
+	c.Transformers = append(c.Transformers, PatchTransformer(p))
+}
+
+// Filter loaded objects before they are created, see ObjectFilter.
+type WithObjectFilter ObjectFilter
+
+func (f WithObjectFilter) ApplyToLoadConfig(c *LoadConfig) {
+	c.Transformers = append(c.Transformers, ObjectFilter(f))
+}
+
+func (f WithObjectFilter) ApplyToCreateConfig(c *CreateConfig) {
+	c.Transformers = append(c.Transformers, ObjectFilter(f))
+}
+
+// Only server-side dry-run objects and record the differences
+// to the live objects in the given report, instead of creating them.
+type WithDiff struct{ *DiffReport }
+
+func (d WithDiff) ApplyToCreateConfig(c *CreateConfig) {
+	c.Diff = d.DiffReport
+}
+
+// Has no effect on the Waiter, but allows passing the option
+// to CreateAndWaitFromHttp, CreateAndWaitFromFiles and CreateAndWaitFromFolders.
+func (d WithDiff) ApplyToWaiterConfig(*WaiterConfig) {}
+
+// Server-side apply objects instead of creating them.
+type WithServerSideApply ServerSideApplyConfig
+
+func (a WithServerSideApply) ApplyToCreateConfig(c *CreateConfig) {
+	config := ServerSideApplyConfig(a)
+	c.ServerSideApply = &config
+}
+
+// Has no effect on the Waiter, but allows passing the option
+// to CreateAndWaitFromHttp, CreateAndWaitFromFiles and CreateAndWaitFromFolders.
+func (a WithServerSideApply) ApplyToWaiterConfig(*WaiterConfig) {}
+
+// Require wait checks to stay done continuously for the given duration.
+// Any regression within the window restarts it.
+type WithStabilityWindow time.Duration
+
+func (w WithStabilityWindow) ApplyToWaiterConfig(c *WaiterConfig) {
+	c.StabilityWindow = time.Duration(w)
+}
+
+func (w WithStabilityWindow) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, w)
+}
+
+// Maximum number of objects waited on at the same time.
+type WithMaxConcurrency int
+
+func (n WithMaxConcurrency) ApplyToWaiterConfig(c *WaiterConfig) {
+	c.MaxConcurrency = int(n)
+}
+
+func (n WithMaxConcurrency) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, n)
+}
+
+// Create all objects first and wait for their readiness concurrently afterwards.
+type WithConcurrentWaits bool
+
+func (w WithConcurrentWaits) ApplyToCreateConfig(c *CreateConfig) {
+	c.ConcurrentWaits = bool(w)
+}
+
+// Has no effect on the Waiter, but allows passing the option
+// to CreateAndWaitFromHttp, CreateAndWaitFromFiles and CreateAndWaitFromFolders.
+func (w WithConcurrentWaits) ApplyToWaiterConfig(*WaiterConfig) {}
+
+// Registers readiness checks for WaitForReadiness.
+// Overrides existing checks for the same GroupKind, nil entries remove the check.
+type WithReadinessChecks ReadinessChecks
+
+func (r WithReadinessChecks) ApplyToWaiterConfig(c *WaiterConfig) {
+	c.ReadinessChecks = c.ReadinessChecks.merge(ReadinessChecks(r))
+}
+
+func (r WithReadinessChecks) ApplyToClusterConfig(c *ClusterConfig) {
+	c.ReadinessChecks = c.ReadinessChecks.merge(ReadinessChecks(r))
+}
+
+func (r WithReadinessChecks) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, r)
+}
+
 type WithSchemeBuilder runtime.SchemeBuilder
 
 func (sb WithSchemeBuilder) ApplyToClusterConfig(c *ClusterConfig) {
@@ -38,6 +250,10 @@ func (opts WithWaitOptions) ApplyToClusterConfig(c *ClusterConfig) {
 	c.WaitOptions = []WaitOption(opts)
 }
 
+func (opts WithWaitOptions) ApplyToCreateConfig(c *CreateConfig) {
+	c.WaitOptions = append(c.WaitOptions, opts...)
+}
+
 type WithNewHelmFunc NewHelmFunc
 
 func (f WithNewHelmFunc) ApplyToClusterConfig(c *ClusterConfig) {
@@ -50,6 +266,49 @@ func (opts WithHelmOptions) ApplyToClusterConfig(c *ClusterConfig) {
 	c.HelmOptions = []HelmOption(opts)
 }
 
+type WithHttpCacheOptions []HttpCacheOption
+
+func (opts WithHttpCacheOptions) ApplyToClusterConfig(c *ClusterConfig) {
+	c.HttpCacheOptions = []HttpCacheOption(opts)
+}
+
+// Download manifests of CreateAndWaitFromHttp through the HttpCache of the cluster,
+// which stores them under WorkDir.
+type WithCacheHttpDownloads bool
+
+func (c WithCacheHttpDownloads) ApplyToClusterConfig(config *ClusterConfig) {
+	config.CacheHttpDownloads = bool(c)
+}
+
+// Headers sent with every request of the HttpCache, e.g. for authentication.
+type WithHttpHeaders http.Header
+
+func (h WithHttpHeaders) ApplyToHttpCacheConfig(c *HttpCacheConfig) {
+	c.Headers = http.Header(h)
+}
+
+// Only serve responses from the cache of the HttpCache, never go to the network.
+type WithOffline bool
+
+func (o WithOffline) ApplyToHttpCacheConfig(c *HttpCacheConfig) {
+	c.Offline = bool(o)
+}
+
+// Backoff between attempts of the HttpCache to download a url.
+// Steps is the maximum number of attempts, unset fields are defaulted.
+type WithRetryBackoff wait.Backoff
+
+func (b WithRetryBackoff) ApplyToHttpCacheConfig(c *HttpCacheConfig) {
+	c.Backoff = wait.Backoff(b)
+}
+
+// Client used by the HttpCache for downloads.
+type WithHttpClient struct{ *http.Client }
+
+func (h WithHttpClient) ApplyToHttpCacheConfig(c *HttpCacheConfig) {
+	c.Client = h.Client
+}
+
 type WithStdout struct{ io.Writer }
 
 func (w WithStdout) ApplyToHelmConfig(c *HelmConfig) {
@@ -119,3 +378,21 @@ type WithNewCtrlClientFunc NewCtrlClientFunc
 func (f WithNewCtrlClientFunc) ApplyToClusterConfig(c *ClusterConfig) {
 	c.NewCtrlClient = NewCtrlClientFunc(f)
 }
+
+// Persists the inventory of created objects into the given ConfigMap.
+// The Namespace of the ConfigMap may be created from the inventory as well,
+// Cluster.Teardown then deletes it with the ConfigMap last.
+type WithInventoryConfigMap client.ObjectKey
+
+func (key WithInventoryConfigMap) ApplyToClusterConfig(c *ClusterConfig) {
+	k := client.ObjectKey(key)
+	c.InventoryConfigMap = &k
+}
+
+// Persists the inventory of created objects into the given file.
+// Relative paths are relative to the WorkDir of the cluster.
+type WithInventoryFile string
+
+func (f WithInventoryFile) ApplyToClusterConfig(c *ClusterConfig) {
+	c.InventoryFile = string(f)
+}
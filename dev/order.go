@@ -0,0 +1,137 @@
+package dev
+
+import (
+	"sort"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// InstallWave groups objects that only depend on objects of earlier waves.
+type InstallWave int
+
+const (
+	InstallWaveNamespaces InstallWave = iota
+	InstallWaveCRDs
+	// ServiceAccounts and RBAC.
+	InstallWaveRBAC
+	// ConfigMaps, Secrets and other objects workloads depend on,
+	// e.g. Services, StorageClasses, PersistentVolumeClaims and quotas.
+	InstallWaveConfig
+	// Workloads and all other built-in objects.
+	InstallWaveWorkloads
+	InstallWaveCustomResources
+)
+
+// Install waves of built-in kinds, kinds not listed are installed with the workloads.
+var installWaveGroupKinds = map[schema.GroupKind]InstallWave{
+	{Kind: "Namespace"}: InstallWaveNamespaces,
+	crdGroupKind:        InstallWaveCRDs,
+
+	{Kind: "ServiceAccount"}:                                         InstallWaveRBAC,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:        InstallWaveRBAC,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: InstallWaveRBAC,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:               InstallWaveRBAC,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:        InstallWaveRBAC,
+
+	{Kind: "ConfigMap"}:                                 InstallWaveConfig,
+	{Kind: "Secret"}:                                    InstallWaveConfig,
+	{Kind: "ResourceQuota"}:                             InstallWaveConfig,
+	{Kind: "LimitRange"}:                                InstallWaveConfig,
+	{Kind: "Service"}:                                   InstallWaveConfig,
+	{Kind: "PersistentVolume"}:                          InstallWaveConfig,
+	{Kind: "PersistentVolumeClaim"}:                     InstallWaveConfig,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:     InstallWaveConfig,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}: InstallWaveConfig,
+	{Group: "networking.k8s.io", Kind: "NetworkPolicy"}: InstallWaveConfig,
+	{Group: "policy", Kind: "PodDisruptionBudget"}:      InstallWaveConfig,
+	// not part of the built-in scheme.
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}: InstallWaveWorkloads,
+}
+
+// Scheme used to tell built-in kinds from custom resources.
+var builtinScheme = func() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apiextensionsv1.AddToScheme(scheme)
+	return scheme
+}()
+
+// Sorts the objects into install waves, so objects are created after the objects they depend on,
+// like Namespaces before the objects in them and CRDs before their custom resources.
+// The order of objects within the same wave is preserved.
+func SortObjectsByInstallWave(objects []unstructured.Unstructured) {
+	waves := installWaves(objects)
+	indices := make([]int, len(objects))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return waves[indices[i]] < waves[indices[j]]
+	})
+
+	sorted := make([]unstructured.Unstructured, len(objects))
+	for i, idx := range indices {
+		sorted[i] = objects[idx]
+	}
+	copy(objects, sorted)
+}
+
+// Groups the objects by install wave, see SortObjectsByInstallWave.
+// Waves without objects are omitted.
+func GroupObjectsByInstallWave(objects []unstructured.Unstructured) [][]unstructured.Unstructured {
+	waves := installWaves(objects)
+	byWave := map[InstallWave][]unstructured.Unstructured{}
+	for i := range objects {
+		byWave[waves[i]] = append(byWave[waves[i]], objects[i])
+	}
+
+	var out [][]unstructured.Unstructured
+	for wave := InstallWaveNamespaces; wave <= InstallWaveCustomResources; wave++ {
+		if len(byWave[wave]) > 0 {
+			out = append(out, byWave[wave])
+		}
+	}
+	return out
+}
+
+// Returns the install wave of each object.
+func installWaves(objects []unstructured.Unstructured) []InstallWave {
+	crdGKs := crdGroupKinds(objects)
+	waves := make([]InstallWave, len(objects))
+	for i := range objects {
+		waves[i] = installWave(objects[i].GroupVersionKind().GroupKind(), crdGKs)
+	}
+	return waves
+}
+
+func installWave(gk schema.GroupKind, crdGKs map[schema.GroupKind]string) InstallWave {
+	if _, ok := crdGKs[gk]; ok {
+		return InstallWaveCustomResources
+	}
+	if wave, ok := installWaveGroupKinds[gk]; ok {
+		return wave
+	}
+	if builtinScheme.IsGroupRegistered(gk.Group) {
+		return InstallWaveWorkloads
+	}
+	return InstallWaveCustomResources
+}
+
+// Returns the GroupKinds defined by the CRDs within objects, mapped to the name of the CRD.
+func crdGroupKinds(objects []unstructured.Unstructured) map[schema.GroupKind]string {
+	gks := map[schema.GroupKind]string{}
+	for i := range objects {
+		obj := &objects[i]
+		if obj.GroupVersionKind().GroupKind() != crdGroupKind {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		gks[schema.GroupKind{Group: group, Kind: kind}] = obj.GetName()
+	}
+	return gks
+}
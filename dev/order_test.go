@@ -0,0 +1,75 @@
+package dev
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestObject(apiVersion, kind, name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	return obj
+}
+
+func newTestCRD(group, kind string) unstructured.Unstructured {
+	crd := newTestObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", kind+"."+group)
+	_ = unstructured.SetNestedField(crd.Object, group, "spec", "group")
+	_ = unstructured.SetNestedField(crd.Object, kind, "spec", "names", "kind")
+	return crd
+}
+
+func objectNames(objects []unstructured.Unstructured) []string {
+	names := make([]string, len(objects))
+	for i := range objects {
+		names[i] = objects[i].GetName()
+	}
+	return names
+}
+
+func TestSortObjectsByInstallWave(t *testing.T) {
+	objects := []unstructured.Unstructured{
+		newTestObject("test.devkube.io/v1", "Cheese", "gouda"),
+		newTestObject("apps/v1", "Deployment", "deploy"),
+		newTestObject("v1", "ConfigMap", "cm"),
+		newTestObject("unknown.devkube.io/v1", "Cracker", "cracker"),
+		newTestObject("rbac.authorization.k8s.io/v1", "Role", "role"),
+		newTestObject("v1", "Secret", "secret"),
+		newTestCRD("test.devkube.io", "Cheese"),
+		newTestObject("apiregistration.k8s.io/v1", "APIService", "apiservice"),
+		newTestObject("v1", "Namespace", "ns"),
+		newTestObject("v1", "ServiceAccount", "sa"),
+	}
+
+	SortObjectsByInstallWave(objects)
+	assert.Equal(t, []string{
+		"ns",
+		"Cheese.test.devkube.io",
+		"role", "sa",
+		"cm", "secret",
+		"deploy", "apiservice",
+		"gouda", "cracker",
+	}, objectNames(objects))
+}
+
+func TestGroupObjectsByInstallWave(t *testing.T) {
+	waves := GroupObjectsByInstallWave([]unstructured.Unstructured{
+		newTestObject("test.devkube.io/v1", "Cheese", "gouda"),
+		newTestObject("apps/v1", "Deployment", "deploy-1"),
+		newTestObject("v1", "Namespace", "ns"),
+		newTestObject("apps/v1", "Deployment", "deploy-2"),
+	})
+
+	var names [][]string
+	for _, wave := range waves {
+		names = append(names, objectNames(wave))
+	}
+	assert.Equal(t, [][]string{
+		{"ns"},
+		{"deploy-1", "deploy-2"},
+		{"gouda"},
+	}, names)
+}
@@ -0,0 +1,219 @@
+package dev
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Patch modifies the loaded objects matching Target.
+type Patch struct {
+	// Objects to patch.
+	// When empty, the patch has to be an object and targets the object
+	// with its apiVersion, kind, name and namespace.
+	Target PatchTarget
+	// Type of the patch.
+	// When empty, lists are applied as RFC 6902 JSON patches, objects as strategic merge patches
+	// for built-in kinds and as JSON merge patches for other kinds, like custom resources.
+	Type types.PatchType
+	// Patch in YAML or JSON.
+	Patch []byte
+}
+
+// PatchTarget selects the objects a patch applies to,
+// or the objects an ObjectFilter includes or excludes.
+// Empty fields match all objects.
+type PatchTarget struct {
+	Group, Version, Kind string
+	// Name of the object, may contain path.Match wildcards like "*".
+	Name      string
+	Namespace string
+	// Label selector, e.g. "app=operator".
+	LabelSelector string
+	// Selector for annotations in label selector syntax, e.g. "example.com/optional=true".
+	AnnotationSelector string
+}
+
+// Loads a patch from a YAML or JSON file, see Patch.
+func LoadPatchFromFile(filePath string, target PatchTarget) (Patch, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return Patch{}, fmt.Errorf("reading %s: %w", filePath, err)
+	}
+	return Patch{Target: target, Patch: content}, nil
+}
+
+// Applies the patches in order.
+// Each patch has to match at least one object, so patches don't silently go stale.
+type PatchTransformer []Patch
+
+func (t PatchTransformer) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	for i, patch := range t {
+		if err := patch.apply(objects); err != nil {
+			return nil, fmt.Errorf("applying patch at index %d: %w", i, err)
+		}
+	}
+	return objects, nil
+}
+
+func (p Patch) apply(objects []unstructured.Unstructured) error {
+	patchJSON, err := yaml.YAMLToJSON(p.Patch)
+	if err != nil {
+		return fmt.Errorf("converting patch to json: %w", err)
+	}
+	patchJSON = bytes.TrimSpace(patchJSON)
+	isList := bytes.HasPrefix(patchJSON, []byte("["))
+
+	target := p.Target
+	if target == (PatchTarget{}) {
+		if isList {
+			return fmt.Errorf("json patches need a target")
+		}
+		if target, err = patchTargetFromObject(patchJSON); err != nil {
+			return err
+		}
+	}
+	selector, annotationSelector, err := target.selectors()
+	if err != nil {
+		return err
+	}
+
+	var matched bool
+	for i := range objects {
+		obj := &objects[i]
+		if ok, err := target.matches(obj, selector, annotationSelector); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		matched = true
+
+		patchType := p.Type
+		if len(patchType) == 0 {
+			patchType = defaultPatchType(obj.GroupVersionKind(), isList)
+		}
+		if err := applyPatch(obj, patchType, patchJSON); err != nil {
+			return fmt.Errorf("%s %s: %w",
+				obj.GroupVersionKind(), client.ObjectKeyFromObject(obj), err)
+		}
+	}
+	if !matched {
+		return fmt.Errorf("no object matches %+v", target)
+	}
+	return nil
+}
+
+// Derives the target of a patch from the apiVersion, kind, name and namespace of the patch.
+func patchTargetFromObject(patchJSON []byte) (PatchTarget, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(patchJSON); err != nil {
+		return PatchTarget{}, fmt.Errorf("patch without target has to be an object: %w", err)
+	}
+	gvk := obj.GroupVersionKind()
+	if len(gvk.Kind) == 0 || len(obj.GetName()) == 0 {
+		return PatchTarget{}, fmt.Errorf("patch without target has to carry kind and name")
+	}
+	return PatchTarget{
+		Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind,
+		Name: obj.GetName(), Namespace: obj.GetNamespace(),
+	}, nil
+}
+
+// Parses the label and annotation selectors of the target.
+func (t PatchTarget) selectors() (selector, annotationSelector labels.Selector, err error) {
+	if selector, err = labels.Parse(t.LabelSelector); err != nil {
+		return nil, nil, fmt.Errorf("parsing label selector: %w", err)
+	}
+	if annotationSelector, err = labels.Parse(t.AnnotationSelector); err != nil {
+		return nil, nil, fmt.Errorf("parsing annotation selector: %w", err)
+	}
+	return selector, annotationSelector, nil
+}
+
+func (t PatchTarget) matches(
+	obj *unstructured.Unstructured, selector, annotationSelector labels.Selector,
+) (bool, error) {
+	gvk := obj.GroupVersionKind()
+	if (len(t.Group) > 0 && t.Group != gvk.Group) ||
+		(len(t.Version) > 0 && t.Version != gvk.Version) ||
+		(len(t.Kind) > 0 && t.Kind != gvk.Kind) ||
+		(len(t.Namespace) > 0 && t.Namespace != obj.GetNamespace()) ||
+		!selector.Matches(labels.Set(obj.GetLabels())) ||
+		!annotationSelector.Matches(labels.Set(obj.GetAnnotations())) {
+		return false, nil
+	}
+	if len(t.Name) == 0 {
+		return true, nil
+	}
+	ok, err := path.Match(t.Name, obj.GetName())
+	if err != nil {
+		return false, fmt.Errorf("matching name %q: %w", t.Name, err)
+	}
+	return ok, nil
+}
+
+func defaultPatchType(gvk schema.GroupVersionKind, isList bool) types.PatchType {
+	switch {
+	case isList:
+		return types.JSONPatchType
+	case builtinScheme.Recognizes(gvk):
+		return types.StrategicMergePatchType
+	default:
+		return types.MergePatchType
+	}
+}
+
+func applyPatch(obj *unstructured.Unstructured, patchType types.PatchType, patchJSON []byte) error {
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch patchType {
+	case types.StrategicMergePatchType:
+		dataStruct, err := builtinScheme.New(obj.GroupVersionKind())
+		if err != nil {
+			return fmt.Errorf("strategic merge patches only support built-in kinds: %w", err)
+		}
+		patched, err = strategicpatch.StrategicMergePatch(original, patchJSON, dataStruct)
+		if err != nil {
+			return fmt.Errorf("strategic merge patch: %w", err)
+		}
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patchJSON)
+		if err != nil {
+			return fmt.Errorf("json merge patch: %w", err)
+		}
+	case types.JSONPatchType:
+		jsonPatch, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return fmt.Errorf("decoding json patch: %w", err)
+		}
+		patched, err = jsonPatch.Apply(original)
+		if err != nil {
+			return fmt.Errorf("json patch: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported patch type %q", patchType)
+	}
+
+	result := &unstructured.Unstructured{}
+	if err := result.UnmarshalJSON(patched); err != nil {
+		return err
+	}
+	obj.Object = result.Object
+	return nil
+}
@@ -0,0 +1,117 @@
+package dev
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func loadPatchTestObjects(t *testing.T) []unstructured.Unstructured {
+	t.Helper()
+	objects, err := LoadKubernetesObjectsFromFile("testdata/deployment.yaml", WithSourceLocations(true))
+	require.NoError(t, err)
+	return append(objects, newTestObject("test.devkube.io/v1", "Cheese", "gouda"))
+}
+
+func TestPatchTransformer(t *testing.T) {
+	objects, err := PatchTransformer{
+		{
+			// strategic merge, targeted by the patch itself.
+			Patch: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-deployment
+  namespace: test-namespace
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: test-container
+        env:
+        - name: DEBUG
+          value: "true"
+`),
+		},
+		{
+			// RFC 6902, targeted by label selector.
+			Target: PatchTarget{Kind: "Deployment", LabelSelector: "test-label=test-value"},
+			Patch:  []byte(`[{"op": "add", "path": "/metadata/labels/patched", "value": "json"}]`),
+		},
+		{
+			// JSON merge patch for custom resources, targeted by name glob.
+			Target: PatchTarget{Group: "test.devkube.io", Name: "gou*"},
+			Patch:  []byte(`{"spec": {"age": "old"}}`),
+		},
+	}.Transform(loadPatchTestObjects(t))
+	require.NoError(t, err)
+
+	deployment := &appsv1.Deployment{}
+	require.NoError(t, copyFromUnstructured(&objects[0], deployment))
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	assert.Equal(t, []corev1.Container{{
+		Name:  "test-container",
+		Image: "test-image:1.2.3",
+		Env:   []corev1.EnvVar{{Name: "DEBUG", Value: "true"}},
+	}}, deployment.Spec.Template.Spec.Containers)
+	assert.Equal(t, "json", deployment.Labels["patched"])
+	_, ok := GetSourceLocation(deployment)
+	assert.True(t, ok)
+
+	age, _, _ := unstructured.NestedString(objects[1].Object, "spec", "age")
+	assert.Equal(t, "old", age)
+}
+
+func TestPatchTransformer_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch Patch
+		err   string
+	}{
+		{
+			name:  "no match",
+			patch: Patch{Target: PatchTarget{Name: "missing"}, Patch: []byte(`{}`)},
+			err:   "no object matches",
+		},
+		{
+			name:  "json patch without target",
+			patch: Patch{Patch: []byte(`[]`)},
+			err:   "json patches need a target",
+		},
+		{
+			name:  "strategic merge for custom resource",
+			patch: Patch{Target: PatchTarget{Kind: "Cheese"}, Type: types.StrategicMergePatchType, Patch: []byte(`{}`)},
+			err:   "only support built-in kinds",
+		},
+		{
+			name:  "failing json patch",
+			patch: Patch{Target: PatchTarget{Kind: "Cheese"}, Patch: []byte(`[{"op": "remove", "path": "/spec/missing"}]`)},
+			err:   "json patch",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := PatchTransformer{test.patch}.Transform(loadPatchTestObjects(t))
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestLoadPatchFromFile(t *testing.T) {
+	patchFile := filepath.Join(t.TempDir(), "patch.yaml")
+	require.NoError(t, os.WriteFile(patchFile, []byte("spec:\n  replicas: 2\n"), 0o644))
+	patch, err := LoadPatchFromFile(patchFile, PatchTarget{Kind: "Deployment"})
+	require.NoError(t, err)
+
+	objects, err := LoadKubernetesObjectsFromFile("testdata/deployment.yaml", WithPatches{patch})
+	require.NoError(t, err)
+	replicas, _, _ := unstructured.NestedInt64(objects[0].Object, "spec", "replicas")
+	assert.Equal(t, int64(2), replicas)
+}
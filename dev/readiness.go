@@ -0,0 +1,254 @@
+package dev
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadinessCheckFunc reports whether the given object is considered ready.
+// The reader may be used to look up related objects and
+// the scheme to convert the object into a typed or unstructured representation.
+type ReadinessCheckFunc func(
+	ctx context.Context, obj client.Object,
+	reader client.Reader, scheme *runtime.Scheme,
+) (done bool, err error)
+
+// ReadinessChecks maps GroupKinds to the function used to check their readiness.
+type ReadinessChecks map[schema.GroupKind]ReadinessCheckFunc
+
+// Returns the readiness checks that are registered by default.
+func DefaultReadinessChecks() ReadinessChecks {
+	return ReadinessChecks{
+		{Group: "apps", Kind: "Deployment"}: ConditionReadinessCheck(
+			"Available", metav1.ConditionTrue),
+		{Group: "apps", Kind: "StatefulSet"}: checkStatefulSetReadiness,
+		{Group: "apps", Kind: "DaemonSet"}:   checkDaemonSetReadiness,
+		{Group: "apps", Kind: "ReplicaSet"}:  checkReplicaSetReadiness,
+		{Group: "batch", Kind: "Job"}:        checkJobReadiness,
+		{Kind: "Pod"}:                        checkPodReadiness,
+		{Kind: "PersistentVolumeClaim"}:      checkPersistentVolumeClaimReadiness,
+		{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: ConditionReadinessCheck(
+			"Established", metav1.ConditionTrue),
+		{Group: "apiregistration.k8s.io", Kind: "APIService"}: ConditionReadinessCheck(
+			"Available", metav1.ConditionTrue),
+	}
+}
+
+// Returns a ReadinessCheckFunc that waits for an object to report the given condition with given status.
+// See checkObjectCondition for details.
+func ConditionReadinessCheck(
+	conditionType string, conditionStatus metav1.ConditionStatus,
+) ReadinessCheckFunc {
+	return func(
+		_ context.Context, obj client.Object,
+		_ client.Reader, scheme *runtime.Scheme,
+	) (done bool, err error) {
+		return checkObjectCondition(obj, conditionType, conditionStatus, scheme)
+	}
+}
+
+// Returns a new ReadinessChecks instance containing all entries of r,
+// overridden by the entries of other.
+func (r ReadinessChecks) merge(other ReadinessChecks) ReadinessChecks {
+	out := ReadinessChecks{}
+	for gk, fn := range r {
+		out[gk] = fn
+	}
+	for gk, fn := range other {
+		out[gk] = fn
+	}
+	return out
+}
+
+// A StatefulSet is ready when all replicas are ready and
+// the replicas selected by the rollout partition are updated.
+func checkStatefulSetReadiness(
+	_ context.Context, obj client.Object,
+	_ client.Reader, _ *runtime.Scheme,
+) (done bool, err error) {
+	sts := &appsv1.StatefulSet{}
+	if err := convertToTyped(obj, sts); err != nil {
+		return false, err
+	}
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false, nil
+	}
+
+	replicas := replicasOrDefault(sts.Spec.Replicas)
+	if sts.Status.ReadyReplicas < replicas {
+		return false, nil
+	}
+	if sts.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return true, nil
+	}
+
+	var partition int32
+	if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		partition = *ru.Partition
+	}
+	if sts.Status.UpdatedReplicas < replicas-partition {
+		return false, nil
+	}
+	if partition == 0 && sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		return false, nil
+	}
+	return true, nil
+}
+
+// A DaemonSet is ready when all scheduled pods are available and updated.
+func checkDaemonSetReadiness(
+	_ context.Context, obj client.Object,
+	_ client.Reader, _ *runtime.Scheme,
+) (done bool, err error) {
+	ds := &appsv1.DaemonSet{}
+	if err := convertToTyped(obj, ds); err != nil {
+		return false, err
+	}
+	if ds.Status.ObservedGeneration < ds.Generation {
+		return false, nil
+	}
+
+	desired := ds.Status.DesiredNumberScheduled
+	if ds.Spec.UpdateStrategy.Type == appsv1.RollingUpdateDaemonSetStrategyType &&
+		ds.Status.UpdatedNumberScheduled < desired {
+		return false, nil
+	}
+	return ds.Status.NumberAvailable >= desired, nil
+}
+
+// A ReplicaSet is ready when all replicas are available.
+func checkReplicaSetReadiness(
+	_ context.Context, obj client.Object,
+	_ client.Reader, _ *runtime.Scheme,
+) (done bool, err error) {
+	rs := &appsv1.ReplicaSet{}
+	if err := convertToTyped(obj, rs); err != nil {
+		return false, err
+	}
+	if rs.Status.ObservedGeneration < rs.Generation {
+		return false, nil
+	}
+	return rs.Status.AvailableReplicas >= replicasOrDefault(rs.Spec.Replicas), nil
+}
+
+// A Job is ready when it completed and errors when it failed.
+func checkJobReadiness(
+	_ context.Context, obj client.Object,
+	_ client.Reader, _ *runtime.Scheme,
+) (done bool, err error) {
+	job := &batchv1.Job{}
+	if err := convertToTyped(obj, job); err != nil {
+		return false, err
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return false, fmt.Errorf("job failed: %s: %s", cond.Reason, cond.Message)
+		}
+	}
+	return false, nil
+}
+
+// A Pod is ready when it reports the Ready condition or has run to completion.
+// Errors when the Pod failed.
+func checkPodReadiness(
+	_ context.Context, obj client.Object,
+	_ client.Reader, _ *runtime.Scheme,
+) (done bool, err error) {
+	pod := &corev1.Pod{}
+	if err := convertToTyped(obj, pod); err != nil {
+		return false, err
+	}
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return true, nil
+	case corev1.PodFailed:
+		return false, fmt.Errorf("pod failed: %s: %s", pod.Status.Reason, pod.Status.Message)
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
+// A PersistentVolumeClaim is ready when it is bound.
+// Claims of a StorageClass with WaitForFirstConsumer binding are considered ready
+// until a consumer was scheduled, because they will not bind before that.
+func checkPersistentVolumeClaimReadiness(
+	ctx context.Context, obj client.Object,
+	reader client.Reader, _ *runtime.Scheme,
+) (done bool, err error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := convertToTyped(obj, pvc); err != nil {
+		return false, err
+	}
+	switch pvc.Status.Phase {
+	case corev1.ClaimBound:
+		return true, nil
+	case corev1.ClaimLost:
+		return false, fmt.Errorf("persistent volume claim lost its volume %q", pvc.Spec.VolumeName)
+	}
+
+	const selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+	if pvc.Spec.StorageClassName == nil ||
+		len(*pvc.Spec.StorageClassName) == 0 ||
+		len(pvc.Annotations[selectedNodeAnnotation]) > 0 {
+		return false, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := reader.Get(ctx, client.ObjectKey{
+		Name: *pvc.Spec.StorageClassName,
+	}, sc); errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("getting StorageClass: %w", err)
+	}
+	return sc.VolumeBindingMode != nil &&
+		*sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer, nil
+}
+
+// Converts the given object into the typed out object.
+func convertToTyped(obj client.Object, out runtime.Object) error {
+	unstrObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		unstrObj = &unstructured.Unstructured{}
+		var err error
+		unstrObj.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return fmt.Errorf("can't convert to unstructured: %w", err)
+		}
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		unstrObj.Object, out); err != nil {
+		return fmt.Errorf("can't convert from unstructured: %w", err)
+	}
+	return nil
+}
+
+// Returns the value of a replicas field, defaulting to 1 when unset.
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
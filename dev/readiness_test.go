@@ -0,0 +1,308 @@
+package dev
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testCRGK = schema.GroupKind{Group: "test.devkube.io", Kind: "Cheese"}
+
+func newTestCR(phase string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(testCRGK.WithVersion("v1"))
+	obj.SetName("gouda")
+	obj.SetNamespace("test")
+	if len(phase) > 0 {
+		obj.Object["status"] = map[string]interface{}{
+			"phase": phase,
+		}
+	}
+	return obj
+}
+
+func phaseReadinessCheck(
+	_ context.Context, obj client.Object,
+	_ client.Reader, _ *runtime.Scheme,
+) (bool, error) {
+	phase, _, err := unstructured.NestedString(
+		obj.(*unstructured.Unstructured).Object, "status", "phase")
+	return phase == "Ripe", err
+}
+
+func TestWaiter_WaitForReadiness(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, appsv1.AddToScheme(scheme))
+	ctx := context.Background()
+
+	t.Run("unknown type", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		w := NewWaiter(c, scheme)
+
+		err := w.WaitForReadiness(ctx, newTestCR("Ripe"))
+		var unknownTypeErr *UnknownTypeError
+		require.ErrorAs(t, err, &unknownTypeErr)
+		assert.Equal(t, testCRGK, unknownTypeErr.GK)
+	})
+
+	t.Run("registered as default option", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newTestCR("Ripe")).Build()
+		w := NewWaiter(c, scheme, WithReadinessChecks{
+			testCRGK: phaseReadinessCheck,
+		})
+
+		require.NoError(t, w.WaitForReadiness(ctx, newTestCR("")))
+	})
+
+	t.Run("registered per call", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newTestCR("Ripe")).Build()
+		w := NewWaiter(c, scheme)
+
+		require.NoError(t, w.WaitForReadiness(ctx, newTestCR(""),
+			WithReadinessChecks{testCRGK: phaseReadinessCheck}))
+
+		// per call options must not leak into the waiter.
+		_, ok := w.config.ReadinessChecks[testCRGK]
+		assert.False(t, ok)
+	})
+
+	t.Run("unregister built-in", func(t *testing.T) {
+		deploy := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(deploy).Build()
+		w := NewWaiter(c, scheme, WithReadinessChecks{
+			{Group: "apps", Kind: "Deployment"}: nil,
+		})
+
+		err := w.WaitForReadiness(ctx, deploy)
+		var unknownTypeErr *UnknownTypeError
+		require.ErrorAs(t, err, &unknownTypeErr)
+	})
+}
+
+func TestDefaultReadinessChecks(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	wffc := storagev1.VolumeBindingWaitForFirstConsumer
+	immediate := storagev1.VolumeBindingImmediate
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&storagev1.StorageClass{
+			ObjectMeta:        metav1.ObjectMeta{Name: "wffc"},
+			VolumeBindingMode: &wffc,
+		},
+		&storagev1.StorageClass{
+			ObjectMeta:        metav1.ObjectMeta{Name: "immediate"},
+			VolumeBindingMode: &immediate,
+		},
+	).Build()
+
+	tests := []struct {
+		name      string
+		object    client.Object
+		result    bool
+		expectErr bool
+	}{
+		{
+			name: "statefulset ready",
+			object: &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: ptr.To[int32](2),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						Type: appsv1.RollingUpdateStatefulSetStrategyType,
+					},
+				},
+				Status: appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					ReadyReplicas:      2,
+					UpdatedReplicas:    2,
+					CurrentRevision:    "a",
+					UpdateRevision:     "a",
+				},
+			},
+			result: true,
+		},
+		{
+			name: "statefulset rolling out",
+			object: &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: ptr.To[int32](2),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						Type: appsv1.RollingUpdateStatefulSetStrategyType,
+					},
+				},
+				Status: appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					ReadyReplicas:      2,
+					UpdatedReplicas:    1,
+					CurrentRevision:    "a",
+					UpdateRevision:     "b",
+				},
+			},
+			result: false,
+		},
+		{
+			name: "daemonset ready",
+			object: &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec: appsv1.DaemonSetSpec{
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+						Type: appsv1.RollingUpdateDaemonSetStrategyType,
+					},
+				},
+				Status: appsv1.DaemonSetStatus{
+					ObservedGeneration:     1,
+					DesiredNumberScheduled: 3,
+					UpdatedNumberScheduled: 3,
+					NumberAvailable:        3,
+				},
+			},
+			result: true,
+		},
+		{
+			name: "daemonset outdated",
+			object: &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: appsv1.DaemonSetStatus{
+					ObservedGeneration:     1,
+					DesiredNumberScheduled: 3,
+					UpdatedNumberScheduled: 3,
+					NumberAvailable:        3,
+				},
+			},
+			result: false,
+		},
+		{
+			name: "replicaset unavailable",
+			object: &appsv1.ReplicaSet{
+				Status: appsv1.ReplicaSetStatus{},
+			},
+			result: false,
+		},
+		{
+			name: "job complete",
+			object: &batchv1.Job{
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+			result: true,
+		},
+		{
+			name: "job failed",
+			object: &batchv1.Job{
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "pod ready",
+			object: &corev1.Pod{
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					Conditions: []corev1.PodCondition{
+						{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+			result: true,
+		},
+		{
+			name: "pod succeeded",
+			object: &corev1.Pod{
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+			},
+			result: true,
+		},
+		{
+			name: "pvc bound",
+			object: &corev1.PersistentVolumeClaim{
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+			},
+			result: true,
+		},
+		{
+			name: "pvc pending",
+			object: &corev1.PersistentVolumeClaim{
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: ptr.To("immediate"),
+				},
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+			},
+			result: false,
+		},
+		{
+			name: "pvc waiting for first consumer",
+			object: &corev1.PersistentVolumeClaim{
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: ptr.To("wffc"),
+				},
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+			},
+			result: true,
+		},
+		{
+			name: "unstructured apiservice",
+			object: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "apiregistration.k8s.io/v1",
+					"kind":       "APIService",
+					"status": map[string]interface{}{
+						"conditions": []interface{}{
+							map[string]interface{}{
+								"type":   "Available",
+								"status": "True",
+							},
+						},
+					},
+				},
+			},
+			result: true,
+		},
+	}
+
+	checks := DefaultReadinessChecks()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gvk, err := apiutil.GVKForObject(test.object, scheme)
+			require.NoError(t, err)
+			checkFn := checks[gvk.GroupKind()]
+			require.NotNil(t, checkFn)
+
+			done, err := checkFn(context.Background(), test.object, reader, scheme)
+			if test.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.result, done)
+		})
+	}
+}
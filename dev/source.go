@@ -0,0 +1,108 @@
+package dev
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation recording where a loaded object has been loaded from, see SourceLocation.
+// Only set by the loaders when enabled via WithSourceLocations.
+// Cluster strips the annotation before objects are sent to the cluster.
+const SourceAnnotation = "devkube.mt-sre.io/source"
+
+// SourceLocation describes where an object has been loaded from.
+type SourceLocation struct {
+	// File path, URL or kustomization directory.
+	Path string `json:"path"`
+	// Index of the document within the file, starting at 0.
+	// Documents without content, like blank or comment-only ones, are counted as well.
+	Document int `json:"document"`
+	// Line the document starts at, starting at 1.
+	// 0 when unknown, e.g. for objects built by kustomize.
+	Line int `json:"line,omitempty"`
+}
+
+func (l SourceLocation) String() string {
+	if l.Line > 0 {
+		return fmt.Sprintf("%s:%d (document %d)", l.Path, l.Line, l.Document)
+	}
+	return fmt.Sprintf("%s (document %d)", l.Path, l.Document)
+}
+
+// Returns where the object has been loaded from,
+// as recorded in the SourceAnnotation by the loaders, see WithSourceLocations.
+func GetSourceLocation(obj metav1.Object) (SourceLocation, bool) {
+	value, ok := obj.GetAnnotations()[SourceAnnotation]
+	if !ok {
+		return SourceLocation{}, false
+	}
+	var loc SourceLocation
+	if err := json.Unmarshal([]byte(value), &loc); err != nil {
+		return SourceLocation{}, false
+	}
+	return loc, true
+}
+
+// Removes the SourceAnnotation from the object.
+func RemoveSourceLocation(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[SourceAnnotation]; !ok {
+		return
+	}
+	delete(annotations, SourceAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+}
+
+func setSourceLocation(obj metav1.Object, loc SourceLocation) {
+	// can't fail for a struct of strings and ints.
+	value, _ := json.Marshal(loc)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[SourceAnnotation] = string(value)
+	obj.SetAnnotations(annotations)
+}
+
+// Strips the SourceAnnotation from the object while fn sends it to the cluster
+// and restores it afterwards, so callers can still look it up.
+func withoutSourceLocation(obj metav1.Object, fn func() error) error {
+	loc, ok := GetSourceLocation(obj)
+	if !ok {
+		return fn()
+	}
+	RemoveSourceLocation(obj)
+	defer setSourceLocation(obj, loc)
+	return fn()
+}
+
+// Runs fn, which may overwrite the object with the copy from the cluster, e.g. while waiting,
+// and restores the SourceAnnotation afterwards. Errors of fn are prefixed with the location.
+func keepSourceLocation(obj metav1.Object, fn func() error) error {
+	loc, ok := GetSourceLocation(obj)
+	err := fn()
+	if !ok {
+		return err
+	}
+	setSourceLocation(obj, loc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", loc, err)
+	}
+	return nil
+}
+
+// Prefixes err with the location the object has been loaded from, if known.
+func wrapWithSourceLocation(obj metav1.Object, err error) error {
+	if err == nil {
+		return nil
+	}
+	if loc, ok := GetSourceLocation(obj); ok {
+		return fmt.Errorf("%s: %w", loc, err)
+	}
+	return err
+}
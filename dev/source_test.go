@@ -0,0 +1,186 @@
+package dev
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func sourceLocations(t *testing.T, objs []client.Object) []SourceLocation {
+	t.Helper()
+	locs := make([]SourceLocation, len(objs))
+	for i, obj := range objs {
+		loc, ok := GetSourceLocation(obj)
+		require.True(t, ok, "no source location on %s", obj.GetName())
+		locs[i] = loc
+	}
+	return locs
+}
+
+func TestLoadKubernetesObjectsFromBytes_SourceLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []SourceLocation
+	}{
+		{
+			name: "yaml",
+			manifest: "# leading comment\n" +
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n" +
+				"--- # comment\n# only a comment\n---\n\n" +
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n",
+			expected: []SourceLocation{
+				{Path: "manifest", Document: 0, Line: 2},
+				{Path: "manifest", Document: 2, Line: 10},
+			},
+		},
+		{
+			name:     "empty documents",
+			manifest: "---\n# c\n---\n\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+			expected: []SourceLocation{
+				{Path: "manifest", Document: 2, Line: 6},
+			},
+		},
+		{
+			name: "crlf",
+			manifest: "apiVersion: v1\r\nkind: ConfigMap\r\nmetadata:\r\n  name: a\r\n" +
+				"---\r\napiVersion: v1\r\nkind: ConfigMap\r\nmetadata:\r\n  name: b\r\n",
+			expected: []SourceLocation{
+				{Path: "manifest", Document: 0, Line: 1},
+				{Path: "manifest", Document: 1, Line: 6},
+			},
+		},
+		{
+			name: "list",
+			manifest: "apiVersion: v1\nkind: List\nitems:\n" +
+				"- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: a\n" +
+				"- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: b\n",
+			expected: []SourceLocation{
+				{Path: "manifest", Document: 0, Line: 1},
+				{Path: "manifest", Document: 0, Line: 1},
+			},
+		},
+		{
+			name: "json",
+			manifest: "\n" +
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a"}}` + "\n\n" +
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "b"}}`,
+			expected: []SourceLocation{
+				{Path: "manifest", Document: 0, Line: 2},
+				{Path: "manifest", Document: 1, Line: 4},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects, err := LoadKubernetesObjectsFromBytes([]byte(test.manifest), WithSourceLocations(true))
+			require.NoError(t, err)
+
+			objs := make([]client.Object, len(objects))
+			for i := range objects {
+				objs[i] = &objects[i]
+			}
+			assert.Equal(t, test.expected, sourceLocations(t, objs))
+		})
+	}
+}
+
+func TestLoadKubernetesObjectsFromFile_SourceLocation(t *testing.T) {
+	objects, err := LoadKubernetesObjectsFromFile("testdata/deployment.yaml")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	// opt-in, so objects can be created with other clients as they are.
+	_, ok := GetSourceLocation(&objects[0])
+	assert.False(t, ok)
+
+	objects, err = LoadKubernetesObjectsFromFile("testdata/deployment.yaml", WithSourceLocations(true))
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	loc, ok := GetSourceLocation(&objects[0])
+	require.True(t, ok)
+	assert.Equal(t, "testdata/deployment.yaml", loc.Path)
+	assert.Equal(t, "testdata/deployment.yaml:1 (document 0)", loc.String())
+
+	RemoveSourceLocation(&objects[0])
+	_, ok = GetSourceLocation(&objects[0])
+	assert.False(t, ok)
+}
+
+func TestCluster_createObjectsFromSource_SourceLocation(t *testing.T) {
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  namespace: default\n" +
+		"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n  namespace: default\n"
+	objects, err := LoadKubernetesObjectsFromBytes([]byte(manifest), WithSourceLocations(true))
+	require.NoError(t, err)
+
+	errInvalid := errors.New("invalid")
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Create: func(
+			ctx context.Context, c client.WithWatch,
+			obj client.Object, opts ...client.CreateOption,
+		) error {
+			if _, ok := obj.GetAnnotations()[SourceAnnotation]; ok {
+				t.Errorf("source annotation sent for %s", obj.GetName())
+			}
+			if obj.GetName() == "b" {
+				return errInvalid
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+	ctx := context.Background()
+
+	err = cluster.createObjectsFromSource(ctx, "test", objects)
+	require.ErrorIs(t, err, errInvalid)
+	assert.Contains(t, err.Error(), "manifest:7 (document 1)")
+
+	// the annotation is still available to the caller.
+	_, ok := GetSourceLocation(&objects[0])
+	assert.True(t, ok)
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cluster.CtrlClient.Get(ctx,
+		client.ObjectKey{Namespace: "default", Name: "a"}, cm))
+	assert.NotContains(t, cm.Annotations, SourceAnnotation)
+}
+
+func TestCluster_CreateAndWait_SourceLocation(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	cluster := newTestClusterWithInterceptor(t, interceptor.Funcs{
+		Create: func(
+			context.Context, client.WithWatch, client.Object, ...client.CreateOption,
+		) error {
+			return errInvalid
+		},
+	})
+
+	// source locations are enabled for errors without any option.
+	err := cluster.CreateAndWait(context.Background(), FilesSource{"testdata/deployment.yaml"})
+	require.ErrorIs(t, err, errInvalid)
+	assert.Contains(t, err.Error(), "testdata/deployment.yaml:1 (document 0)")
+}
+
+func TestCluster_CreateAndWait_SourceLocation_WaitTimeout(t *testing.T) {
+	for name, opts := range map[string][]CreateOption{
+		"sequential": {WithTimeout(50 * time.Millisecond)},
+		"concurrent": {WithTimeout(50 * time.Millisecond), WithConcurrentWaits(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cluster := newTestCluster(t)
+
+			// the Deployment never becomes available with the fake client.
+			err := cluster.CreateAndWaitWithOptions(context.Background(),
+				[]ObjectSource{FilesSource{"testdata/deployment.yaml"}}, opts...)
+			var timeoutErr *TimeoutError
+			require.ErrorAs(t, err, &timeoutErr)
+			assert.Contains(t, err.Error(), "testdata/deployment.yaml:1 (document 0)")
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package dev
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	sprig "github.com/go-task/slim-sprig/v3"
+)
+
+// Matches ${VAR} references, $${VAR} escapes them.
+var envSubstRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Renders a manifest according to the given config before it is parsed.
+// Go templates are rendered first, ${VAR} references are substituted afterwards.
+func renderManifest(name string, content []byte, config LoadConfig) ([]byte, error) {
+	if config.TemplateValues != nil {
+		var err error
+		content, err = renderTemplate(name, content, config.TemplateValues)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.EnvSubst != nil {
+		var err error
+		content, err = substituteVars(content, config.EnvSubst)
+		if err != nil {
+			return nil, fmt.Errorf("substituting variables in %s: %w", name, err)
+		}
+	}
+	return content, nil
+}
+
+// Renders content as Go text/template with sprig functions.
+// Referencing missing values is an error.
+func renderTemplate(name string, content []byte, values map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).
+		Funcs(sprig.TxtFuncMap()).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Replaces ${VAR} references with the given values.
+// Referencing missing values is an error, $${VAR} is replaced with a literal ${VAR}.
+func substituteVars(content []byte, values map[string]string) ([]byte, error) {
+	var missing []string
+	out := envSubstRegexp.ReplaceAllFunc(content, func(match []byte) []byte {
+		if bytes.HasPrefix(match, []byte("$$")) {
+			return match[1:]
+		}
+
+		name := string(match[2 : len(match)-1])
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing values for %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
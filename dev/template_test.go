@@ -0,0 +1,54 @@
+package dev
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_renderTemplate(t *testing.T) {
+	out, err := renderTemplate("test", []byte(`name: {{ .name | upper }}`),
+		map[string]interface{}{"name": "gouda"})
+	require.NoError(t, err)
+	assert.Equal(t, "name: GOUDA", string(out))
+
+	_, err = renderTemplate("test", []byte(`name: {{ .missing }}`),
+		map[string]interface{}{"name": "gouda"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+}
+
+func Test_substituteVars(t *testing.T) {
+	out, err := substituteVars(
+		[]byte(`image: ${REGISTRY}/app:${TAG}, literal: $${TAG}, shell: $TAG $$`),
+		map[string]string{"REGISTRY": "quay.io", "TAG": "v1"})
+	require.NoError(t, err)
+	assert.Equal(t, `image: quay.io/app:v1, literal: ${TAG}, shell: $TAG $$`, string(out))
+
+	_, err = substituteVars([]byte(`${A} ${B} ${C}`), map[string]string{"B": "b"})
+	require.EqualError(t, err, "missing values for A, C")
+}
+
+func TestLoadKubernetesObjectsFromBytes_Render(t *testing.T) {
+	manifest := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .name }}
+  namespace: ${NAMESPACE}
+`)
+
+	objects, err := LoadKubernetesObjectsFromBytes(manifest,
+		WithTemplateValues{"name": "cheese"},
+		WithEnvSubst{"NAMESPACE": "test"})
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "cheese", objects[0].GetName())
+	assert.Equal(t, "test", objects[0].GetNamespace())
+
+	_, err = LoadKubernetesObjectsFromBytes(manifest, WithTemplateValues{})
+	require.Error(t, err)
+	_, err = LoadKubernetesObjectsFromBytes(manifest,
+		WithTemplateValues{"name": "cheese"}, WithEnvSubst{})
+	require.ErrorContains(t, err, "missing values for NAMESPACE")
+}
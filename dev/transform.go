@@ -0,0 +1,290 @@
+package dev
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Transformer modifies loaded objects before they are created.
+type Transformer interface {
+	Transform(objects []unstructured.Unstructured) ([]unstructured.Unstructured, error)
+}
+
+// Runs the transformers in order.
+type Transformers []Transformer
+
+func (t Transformers) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	for _, transformer := range t {
+		var err error
+		if objects, err = transformer.Transform(objects); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// Transforms objects with a function.
+type TransformerFunc func(objects []unstructured.Unstructured) ([]unstructured.Unstructured, error)
+
+func (fn TransformerFunc) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	return fn(objects)
+}
+
+// Cluster-scoped built-in kinds, all other built-in kinds are namespaced.
+var clusterScopedGroupKinds = map[schema.GroupKind]bool{
+	{Kind: "Namespace"}:        true,
+	{Kind: "Node"}:             true,
+	{Kind: "PersistentVolume"}: true,
+	{Kind: "ComponentStatus"}:  true,
+	crdGroupKind:               true,
+
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:            true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:        true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: true,
+
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:     true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}:        true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}: true,
+
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:     true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:        true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:          true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}: true,
+
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:               true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                      true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}: true,
+
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                 true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}: true,
+}
+
+// Moves all namespaced objects into Namespace.
+// ServiceAccount subjects of RoleBindings and ClusterRoleBindings
+// referencing moved ServiceAccounts are updated too.
+// Custom resources are considered namespaced,
+// unless their CRD is part of the objects and is cluster-scoped or listed in ClusterScoped.
+type NamespaceTransformer struct {
+	Namespace string
+	// Additional cluster-scoped kinds.
+	ClusterScoped []schema.GroupKind
+}
+
+func (t NamespaceTransformer) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	clusterScoped := map[schema.GroupKind]bool{}
+	for _, gk := range t.ClusterScoped {
+		clusterScoped[gk] = true
+	}
+	for i := range objects {
+		obj := &objects[i]
+		if obj.GroupVersionKind().GroupKind() != crdGroupKind {
+			continue
+		}
+		if scope, _, _ := unstructured.NestedString(obj.Object, "spec", "scope"); scope == "Cluster" {
+			group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+			clusterScoped[schema.GroupKind{Group: group, Kind: kind}] = true
+		}
+	}
+
+	// ServiceAccounts moved into the new namespace.
+	movedServiceAccounts := map[client.ObjectKey]bool{}
+	for i := range objects {
+		obj := &objects[i]
+		gk := obj.GroupVersionKind().GroupKind()
+		if clusterScopedGroupKinds[gk] || clusterScoped[gk] {
+			continue
+		}
+		if gk == (schema.GroupKind{Kind: "ServiceAccount"}) {
+			movedServiceAccounts[client.ObjectKeyFromObject(obj)] = true
+		}
+		obj.SetNamespace(t.Namespace)
+	}
+
+	for i := range objects {
+		obj := &objects[i]
+		gk := obj.GroupVersionKind().GroupKind()
+		if gk.Group != "rbac.authorization.k8s.io" ||
+			(gk.Kind != "RoleBinding" && gk.Kind != "ClusterRoleBinding") {
+			continue
+		}
+		if err := t.updateSubjects(obj, movedServiceAccounts); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func (t NamespaceTransformer) updateSubjects(
+	binding *unstructured.Unstructured, movedServiceAccounts map[client.ObjectKey]bool,
+) error {
+	subjects, found, err := unstructured.NestedSlice(binding.Object, "subjects")
+	if err != nil {
+		return fmt.Errorf("%s %s: %w",
+			binding.GroupVersionKind(), client.ObjectKeyFromObject(binding), err)
+	}
+	if !found {
+		return nil
+	}
+	for _, s := range subjects {
+		subject, ok := s.(map[string]interface{})
+		if !ok || subject["kind"] != "ServiceAccount" {
+			continue
+		}
+		name, _ := subject["name"].(string)
+		namespace, _ := subject["namespace"].(string)
+		if movedServiceAccounts[client.ObjectKey{Namespace: namespace, Name: name}] {
+			subject["namespace"] = t.Namespace
+		}
+	}
+	return unstructured.SetNestedSlice(binding.Object, subjects, "subjects")
+}
+
+// Rewrites container images by image name, like the kustomize images field.
+// Keys are image names without tag or digest, e.g. "quay.io/app/operator".
+// Values are the new image, e.g. "localhost/operator:test" or "localhost/operator".
+// The original tag or digest is kept, when the new image has none.
+type ImageTransformer map[string]string
+
+func (t ImageTransformer) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	for i := range objects {
+		visitContainers(objects[i].Object, func(container map[string]interface{}) {
+			image, ok := container["image"].(string)
+			if !ok {
+				return
+			}
+			name, suffix := splitImage(image)
+			newImage, ok := t[name]
+			if !ok {
+				return
+			}
+			if _, newSuffix := splitImage(newImage); newSuffix == "" {
+				newImage += suffix
+			}
+			container["image"] = newImage
+		})
+	}
+	return objects, nil
+}
+
+// Sets the imagePullPolicy of containers, e.g. to IfNotPresent for images loaded into a kind cluster,
+// that would otherwise be pulled for the latest tag.
+type ImagePullPolicyTransformer struct {
+	// Defaults to IfNotPresent.
+	Policy corev1.PullPolicy
+	// Image names or references the policy is set for, all images when empty.
+	Images []string
+}
+
+func (t ImagePullPolicyTransformer) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	policy := t.Policy
+	if len(policy) == 0 {
+		policy = corev1.PullIfNotPresent
+	}
+	images := map[string]bool{}
+	for _, image := range t.Images {
+		images[image] = true
+	}
+
+	for i := range objects {
+		visitContainers(objects[i].Object, func(container map[string]interface{}) {
+			image, _ := container["image"].(string)
+			name, _ := splitImage(image)
+			if len(images) == 0 || images[image] || images[name] {
+				container["imagePullPolicy"] = string(policy)
+			}
+		})
+	}
+	return objects, nil
+}
+
+// Adds the labels to all objects, overriding existing labels with the same key.
+type LabelsTransformer map[string]string
+
+func (t LabelsTransformer) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	for i := range objects {
+		objects[i].SetLabels(mergeStringMaps(objects[i].GetLabels(), t))
+	}
+	return objects, nil
+}
+
+// Adds the annotations to all objects, overriding existing annotations with the same key.
+type AnnotationsTransformer map[string]string
+
+func (t AnnotationsTransformer) Transform(
+	objects []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	for i := range objects {
+		objects[i].SetAnnotations(mergeStringMaps(objects[i].GetAnnotations(), t))
+	}
+	return objects, nil
+}
+
+func mergeStringMaps(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = map[string]string{}
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// Calls fn for every container within the object, including containers in pod templates
+// of workloads and custom resources.
+func visitContainers(obj interface{}, fn func(container map[string]interface{})) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "containers", "initContainers", "ephemeralContainers":
+				if containers, ok := value.([]interface{}); ok {
+					for _, c := range containers {
+						if container, ok := c.(map[string]interface{}); ok {
+							fn(container)
+						}
+					}
+					continue
+				}
+			}
+			visitContainers(value, fn)
+		}
+	case []interface{}:
+		for _, value := range v {
+			visitContainers(value, fn)
+		}
+	}
+}
+
+// Splits an image reference into its name and its ":tag", "@digest" or ":tag@digest" suffix.
+func splitImage(image string) (name, suffix string) {
+	if i := strings.Index(image, "@"); i != -1 {
+		name, suffix = image[:i], image[i:]
+	} else {
+		name = image
+	}
+	// A colon after the last slash separates the tag, others belong to a registry port.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[:i], name[i:] + suffix
+	}
+	return name, suffix
+}
@@ -0,0 +1,186 @@
+package dev
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const transformTestManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: operator
+  namespace: operator-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: operator
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: operator
+subjects:
+- kind: ServiceAccount
+  name: operator
+  namespace: operator-system
+- kind: ServiceAccount
+  name: other
+  namespace: operator-system
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: cheeses.test.devkube.io
+spec:
+  group: test.devkube.io
+  scope: Cluster
+  names:
+    kind: Cheese
+---
+apiVersion: test.devkube.io/v1
+kind: Cheese
+metadata:
+  name: gouda
+---
+apiVersion: test.devkube.io/v1
+kind: Cracker
+metadata:
+  name: cracker
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: quay.io/app/init@sha256:abc
+      containers:
+      - name: operator
+        image: quay.io/app/operator:v1.0.0
+      - name: proxy
+        image: localhost:5000/proxy
+`
+
+func loadTransformTestObjects(t *testing.T) []unstructured.Unstructured {
+	t.Helper()
+	objects, err := LoadKubernetesObjectsFromBytes([]byte(transformTestManifest))
+	require.NoError(t, err)
+	return objects
+}
+
+func objectKeys(objects []unstructured.Unstructured) []client.ObjectKey {
+	keys := make([]client.ObjectKey, len(objects))
+	for i := range objects {
+		keys[i] = client.ObjectKeyFromObject(&objects[i])
+	}
+	return keys
+}
+
+func TestNamespaceTransformer(t *testing.T) {
+	objects, err := NamespaceTransformer{Namespace: "test"}.
+		Transform(loadTransformTestObjects(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, []client.ObjectKey{
+		{Namespace: "test", Name: "operator"},
+		{Name: "operator"},
+		{Name: "cheeses.test.devkube.io"},
+		{Name: "gouda"},
+		{Namespace: "test", Name: "cracker"},
+		{Namespace: "test", Name: "operator"},
+	}, objectKeys(objects))
+
+	subjects, _, _ := unstructured.NestedSlice(objects[1].Object, "subjects")
+	assert.Equal(t, "test", subjects[0].(map[string]interface{})["namespace"])
+	// not part of the objects.
+	assert.Equal(t, "operator-system", subjects[1].(map[string]interface{})["namespace"])
+
+	objects, err = NamespaceTransformer{
+		Namespace:     "test",
+		ClusterScoped: []schema.GroupKind{{Group: "test.devkube.io", Kind: "Cracker"}},
+	}.Transform(loadTransformTestObjects(t))
+	require.NoError(t, err)
+	assert.Empty(t, objects[4].GetNamespace())
+}
+
+func TestImageTransformers(t *testing.T) {
+	objects, err := Transformers{
+		ImageTransformer{
+			"quay.io/app/operator": "localhost/operator:test",
+			"quay.io/app/init":     "localhost/init",
+			"localhost:5000/proxy": "localhost/proxy:dev",
+		},
+		ImagePullPolicyTransformer{Images: []string{"localhost/operator", "localhost/proxy:dev"}},
+	}.Transform(loadTransformTestObjects(t))
+	require.NoError(t, err)
+
+	deployment := &appsv1.Deployment{}
+	require.NoError(t, copyFromUnstructured(&objects[5], deployment))
+	spec := deployment.Spec.Template.Spec
+	assert.Equal(t, "localhost/init@sha256:abc", spec.InitContainers[0].Image)
+	assert.Empty(t, spec.InitContainers[0].ImagePullPolicy)
+	assert.Equal(t, "localhost/operator:test", spec.Containers[0].Image)
+	assert.Equal(t, "IfNotPresent", string(spec.Containers[0].ImagePullPolicy))
+	assert.Equal(t, "localhost/proxy:dev", spec.Containers[1].Image)
+	assert.Equal(t, "IfNotPresent", string(spec.Containers[1].ImagePullPolicy))
+}
+
+func TestLabelsAndAnnotationsTransformers(t *testing.T) {
+	objects, err := LoadKubernetesObjectsFromFile("testdata/deployment.yaml",
+		WithSourceLocations(true),
+		WithTransformers{
+			LabelsTransformer{"app": "test"},
+			AnnotationsTransformer{"test-annotation": "overridden"},
+		})
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	assert.Equal(t, map[string]string{"app": "test", "test-label": "test-value"},
+		objects[0].GetLabels())
+	assert.Equal(t, "overridden", objects[0].GetAnnotations()["test-annotation"])
+	// source location is retained.
+	_, ok := GetSourceLocation(&objects[0])
+	assert.True(t, ok)
+}
+
+func Test_splitImage(t *testing.T) {
+	tests := []struct{ image, name, suffix string }{
+		{"nginx", "nginx", ""},
+		{"nginx:1.0", "nginx", ":1.0"},
+		{"localhost:5000/nginx", "localhost:5000/nginx", ""},
+		{"localhost:5000/nginx:1.0", "localhost:5000/nginx", ":1.0"},
+		{"nginx@sha256:abc", "nginx", "@sha256:abc"},
+		{"nginx:1.0@sha256:abc", "nginx", ":1.0@sha256:abc"},
+	}
+	for _, test := range tests {
+		name, suffix := splitImage(test.image)
+		assert.Equal(t, test.name, name, test.image)
+		assert.Equal(t, test.suffix, suffix, test.image)
+	}
+}
+
+func TestCluster_CreateAndWait_Transformers(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+
+	err := cluster.CreateAndWaitWithOptions(ctx, []ObjectSource{
+		ObjectsSource{newTestConfigMap("a", "a")},
+		ObjectsSource{newTestConfigMap("b", "b")},
+	}, WithTransformers{NamespaceTransformer{Namespace: "moved"}})
+	require.NoError(t, err)
+
+	for _, name := range []string{"a", "b"} {
+		assert.NoError(t, cluster.CtrlClient.Get(ctx,
+			client.ObjectKey{Namespace: "moved", Name: name}, newTestConfigMap(name, "")))
+	}
+}
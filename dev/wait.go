@@ -3,7 +3,10 @@ package dev
 import (
 	"context"
 	"encoding/json"
+	goerrors "errors"
 	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -19,13 +22,39 @@ import (
 )
 
 const (
-	WaiterDefaultTimeout  = 60 * time.Second
-	WaiterDefaultInterval = time.Second
+	WaiterDefaultTimeout        = 60 * time.Second
+	WaiterDefaultInterval       = time.Second
+	WaiterDefaultMaxConcurrency = 10
 )
 
 type WaiterConfig struct {
 	Timeout  time.Duration
 	Interval time.Duration
+	// Exponential backoff between polls, used instead of the fixed Interval when set.
+	// Steps default to unlimited, so the delay grows until reaching Cap.
+	Backoff *wait.Backoff
+	// Duration a check has to report done continuously before a wait succeeds.
+	// Must be shorter than Timeout, waits error otherwise.
+	StabilityWindow time.Duration
+	// Maximum number of objects waited on at the same time by WaitForAllReadiness.
+	// Values <= 0 use WaiterDefaultMaxConcurrency.
+	MaxConcurrency int
+	// Watch objects for changes instead of polling them every Interval.
+	// Falls back to polling if the client or API does not support watches.
+	Watch bool
+	// Readiness checks used by WaitForReadiness.
+	// Entries override the defaults for the same GroupKind,
+	// nil entries unregister the GroupKind.
+	ReadinessChecks ReadinessChecks
+}
+
+// Returns an error when waits could never succeed within the Timeout.
+func (c *WaiterConfig) validateStabilityWindow() error {
+	if c.StabilityWindow > 0 && c.StabilityWindow >= c.Timeout {
+		return fmt.Errorf("stability window %s must be shorter than timeout %s",
+			c.StabilityWindow, c.Timeout)
+	}
+	return nil
 }
 
 // Sets defaults on the waiter config.
@@ -36,6 +65,23 @@ func (c *WaiterConfig) Default() {
 	if c.Interval == 0 {
 		c.Interval = WaiterDefaultInterval
 	}
+	if c.MaxConcurrency <= 0 {
+		c.MaxConcurrency = WaiterDefaultMaxConcurrency
+	}
+	c.ReadinessChecks = DefaultReadinessChecks().merge(c.ReadinessChecks)
+}
+
+// Returns a function computing the delay between polls.
+func (c *WaiterConfig) delayFunc() wait.DelayFunc {
+	if c.Backoff == nil {
+		return wait.Backoff{Duration: c.Interval}.DelayFunc()
+	}
+
+	b := *c.Backoff
+	if b.Steps == 0 {
+		b.Steps = math.MaxInt32
+	}
+	return b.DelayFunc()
 }
 
 type WaitOption interface {
@@ -75,6 +121,38 @@ func (e *UnknownTypeError) Error() string {
 	return fmt.Sprintf("unknown type: %s", e.GK)
 }
 
+// Waits for the API server to serve the given kind,
+// e.g. after the CRD defining it has been established.
+func (w *Waiter) WaitForKindDiscoverable(
+	ctx context.Context, gvk schema.GroupVersionKind,
+	opts ...WaitOption,
+) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	c := w.config
+	for _, opt := range opts {
+		opt.ApplyToWaiterConfig(&c)
+	}
+
+	log.Info(fmt.Sprintf("waiting %s for %s to be discoverable...", c.Timeout, gvk))
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var mappingErr error
+	err := c.delayFunc().Until(ctx, true, false,
+		func(context.Context) (done bool, err error) {
+			_, mappingErr = w.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+			// retry on all errors, discovery may still be catching up.
+			return mappingErr == nil, nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("waiting for %s to be discoverable: %w: %v", gvk, err, mappingErr)
+	}
+	return nil
+}
+
 // Waits for an object to be considered available.
 func (w *Waiter) WaitForReadiness(
 	ctx context.Context, object client.Object, opts ...WaitOption,
@@ -84,22 +162,68 @@ func (w *Waiter) WaitForReadiness(
 		return fmt.Errorf("could not determine GVK for object: %w", err)
 	}
 
-	// TODO:
-	// Extend by other common types and open up to
-	// register new types and readiness functions.
+	c := w.config
+	for _, opt := range opts {
+		opt.ApplyToWaiterConfig(&c)
+	}
+
 	gk := gvk.GroupKind()
-	switch gk {
-	case schema.GroupKind{
-		Kind: "Deployment", Group: "apps"}:
-		return w.WaitForCondition(ctx, object, "Available", metav1.ConditionTrue, opts...)
+	checkFn := c.ReadinessChecks[gk]
+	if checkFn == nil {
+		return &UnknownTypeError{GK: gk}
+	}
 
-	case schema.GroupKind{
-		Kind: "CustomResourceDefinition", Group: "apiextensions.k8s.io"}:
-		return w.WaitForCondition(ctx, object, "Established", metav1.ConditionTrue, opts...)
+	return w.WaitForObject(
+		ctx, object, "to be ready",
+		func(obj client.Object) (done bool, err error) {
+			return checkFn(ctx, obj, w.client, w.scheme)
+		}, opts...)
+}
 
-	default:
-		return &UnknownTypeError{GK: gk}
+// Waits for all given objects to be considered available.
+// Objects are waited on concurrently, bounded by MaxConcurrency.
+// Objects of types without registered readiness check are skipped.
+// Returns an aggregated error naming every object that failed.
+func (w *Waiter) WaitForAllReadiness(
+	ctx context.Context, objects []client.Object, opts ...WaitOption,
+) error {
+	c := w.config
+	for _, opt := range opts {
+		opt.ApplyToWaiterConfig(&c)
+	}
+	// Options are applied after defaulting.
+	if c.MaxConcurrency <= 0 {
+		c.MaxConcurrency = WaiterDefaultMaxConcurrency
 	}
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, c.MaxConcurrency)
+		errs = make([]error, len(objects))
+	)
+	for i, obj := range objects {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, obj client.Object) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Waiting overwrites the object with the cluster copy, which lacks the SourceAnnotation.
+			errs[i] = keepSourceLocation(obj, func() error {
+				err := w.WaitForReadiness(ctx, obj, opts...)
+				var unknownTypeErr *UnknownTypeError
+				if err == nil || goerrors.As(err, &unknownTypeErr) {
+					return nil
+				}
+
+				gvk, _ := apiutil.GVKForObject(obj, w.scheme)
+				return fmt.Errorf("%s %s: %w", gvk, client.ObjectKeyFromObject(obj), err)
+			})
+		}(i, obj)
+	}
+	wg.Wait()
+
+	return goerrors.Join(errs...)
 }
 
 // Waits for an object to report the given condition with given status.
@@ -140,20 +264,18 @@ func (w *Waiter) WaitForObject(
 	log.Info(fmt.Sprintf("waiting %s on %s %s %s...",
 		c.Timeout, gvk, key, waitReason))
 
-	return wait.PollUntilContextTimeout(ctx, c.Interval, c.Timeout, true,
-		func(ctx context.Context) (done bool, err error) {
-			err = w.client.Get(ctx, client.ObjectKeyFromObject(object), object)
-			if err != nil {
-				//nolint:nilerr // retry on transient errors
+	return w.waitForObjectState(ctx, c, object, gvk, waitReason,
+		func(obj client.Object, exists bool) (done bool, err error) {
+			if !exists {
 				return false, nil
 			}
-
-			return checkFn(object)
+			return checkFn(obj)
 		},
 	)
 }
 
 // Wait for an object to not exist anymore.
+// checkFn may be nil, otherwise the wait also succeeds when it reports done for the existing object.
 func (w *Waiter) WaitToBeGone(
 	ctx context.Context, object client.Object,
 	checkFn func(obj client.Object) (done bool, err error),
@@ -175,23 +297,120 @@ func (w *Waiter) WaitToBeGone(
 	log.Info(fmt.Sprintf("waiting %s for %s %s to be gone...",
 		c.Timeout, gvk, key))
 
-	return wait.PollUntilContextTimeout(
-		ctx, c.Interval, c.Timeout, true,
+	return w.waitForObjectState(ctx, c, object, gvk, "to be gone",
+		func(obj client.Object, exists bool) (done bool, err error) {
+			if !exists {
+				return true, nil
+			}
+			if checkFn == nil {
+				return false, nil
+			}
+			return checkFn(obj)
+		},
+	)
+}
+
+// Condition evaluated against every observed state of an object.
+// exists is false when the object was not found.
+type objectStateCondition func(obj client.Object, exists bool) (done bool, err error)
+
+// Blocks until the given condition is done, errors or the configured timeout is reached.
+// Uses a watch when enabled and supported by the client and falls back to polling otherwise.
+// Returns a *TimeoutError describing the last observed state when the timeout is reached.
+func (w *Waiter) waitForObjectState(
+	ctx context.Context, c WaiterConfig,
+	object client.Object, gvk schema.GroupVersionKind,
+	waitReason string, condition objectStateCondition,
+) error {
+	if err := c.validateStabilityWindow(); err != nil {
+		return err
+	}
+
+	var observed bool
+	stability := stabilityTracker{window: c.StabilityWindow}
+	observingCondition := func(obj client.Object, exists bool) (done bool, err error) {
+		observed = observed || exists
+		done, err = condition(obj, exists)
+		return stability.observe(done), err
+	}
+
+	err := w.waitForObjectStateUntilTimeout(ctx, c, object, gvk, observingCondition)
+	if goerrors.Is(err, context.DeadlineExceeded) {
+		return w.newTimeoutError(ctx, c, object, gvk, waitReason, observed, err)
+	}
+	return err
+}
+
+func (w *Waiter) waitForObjectStateUntilTimeout(
+	ctx context.Context, c WaiterConfig,
+	object client.Object, gvk schema.GroupVersionKind,
+	condition objectStateCondition,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	if wc, ok := w.client.(client.WithWatch); ok && c.Watch {
+		err := w.watchObjectState(ctx, c, wc, object, gvk, condition)
+		if !goerrors.Is(err, errWatchNotPossible) {
+			return err
+		}
+		logr.FromContextOrDiscard(ctx).Info(fmt.Sprintf(
+			"falling back to polling %s %s: %v",
+			gvk, client.ObjectKeyFromObject(object), err))
+	}
+
+	return c.delayFunc().Until(ctx, true, false,
 		func(ctx context.Context) (done bool, err error) {
 			err = w.client.Get(ctx, client.ObjectKeyFromObject(object), object)
 			if errors.IsNotFound(err) {
-				return true, nil
+				return condition(object, false)
+			}
+			if isPermanentError(err) {
+				return false, fmt.Errorf("getting %s %s: %w",
+					gvk, client.ObjectKeyFromObject(object), err)
 			}
 			if err != nil {
 				//nolint:nilerr // retry on transient errors
 				return false, nil
 			}
 
-			return checkFn(object)
+			return condition(object, true)
 		},
 	)
 }
 
+// Tracks whether a check stayed done for the duration of a stability window.
+type stabilityTracker struct {
+	window    time.Duration
+	doneSince time.Time
+}
+
+// Records the latest check result and
+// reports whether the check was done continuously for the whole window.
+// Any result that is not done resets the window.
+func (s *stabilityTracker) observe(done bool) bool {
+	if s.window <= 0 || !done {
+		s.doneSince = time.Time{}
+		return done
+	}
+	if s.doneSince.IsZero() {
+		s.doneSince = time.Now()
+	}
+	return time.Since(s.doneSince) >= s.window
+}
+
+// Reports whether an error returned by the API server will not go away by retrying,
+// e.g. because of missing permissions, an unknown kind or an invalid object.
+func isPermanentError(err error) bool {
+	return errors.IsForbidden(err) ||
+		errors.IsUnauthorized(err) ||
+		errors.IsBadRequest(err) ||
+		errors.IsInvalid(err) ||
+		errors.IsMethodNotSupported(err) ||
+		meta.IsNoMatchError(err) ||
+		runtime.IsNotRegisteredError(err)
+}
+
 // Check if a object condition is in a certain state.
 // Will respect .status.observedGeneration and .status.conditions[].observedGeneration.
 func checkObjectCondition(
@@ -215,24 +434,9 @@ func checkObjectCondition(
 		return false, nil
 	}
 
-	conditionsRaw, ok, err := unstructured.NestedFieldNoCopy(
-		unstrObj.Object, "status", "conditions")
+	conditions, err := objectConditions(unstrObj)
 	if err != nil {
-		return false, fmt.Errorf("could not access .status.conditions: %w", err)
-	}
-	if !ok {
-		// no conditions reported
-		return false, nil
-	}
-
-	// Press into metav1.Condition scheme to be able to work typed.
-	conditionsJSON, err := json.Marshal(conditionsRaw)
-	if err != nil {
-		return false, fmt.Errorf("could not marshal conditions into JSON: %w", err)
-	}
-	var conditions []metav1.Condition
-	if err := json.Unmarshal(conditionsJSON, &conditions); err != nil {
-		return false, fmt.Errorf("could not unmarshal conditions: %w", err)
+		return false, err
 	}
 
 	// Check conditions
@@ -250,3 +454,27 @@ func checkObjectCondition(
 
 	return condition.Status == conditionStatus, nil
 }
+
+// Returns the conditions reported under .status.conditions of the given object.
+func objectConditions(unstrObj *unstructured.Unstructured) ([]metav1.Condition, error) {
+	conditionsRaw, ok, err := unstructured.NestedFieldNoCopy(
+		unstrObj.Object, "status", "conditions")
+	if err != nil {
+		return nil, fmt.Errorf("could not access .status.conditions: %w", err)
+	}
+	if !ok {
+		// no conditions reported
+		return nil, nil
+	}
+
+	// Press into metav1.Condition scheme to be able to work typed.
+	conditionsJSON, err := json.Marshal(conditionsRaw)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal conditions into JSON: %w", err)
+	}
+	var conditions []metav1.Condition
+	if err := json.Unmarshal(conditionsJSON, &conditions); err != nil {
+		return nil, fmt.Errorf("could not unmarshal conditions: %w", err)
+	}
+	return conditions, nil
+}
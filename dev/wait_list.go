@@ -0,0 +1,290 @@
+package dev
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Waits for all objects of the given kind matching the label selector to be considered available
+// and for at least minCount objects to exist.
+// An empty namespace selects objects in all namespaces.
+func (w *Waiter) WaitForObjectsReadiness(
+	ctx context.Context, gvk schema.GroupVersionKind,
+	namespace string, selector labels.Selector, minCount int,
+	opts ...WaitOption,
+) error {
+	c := w.config
+	for _, opt := range opts {
+		opt.ApplyToWaiterConfig(&c)
+	}
+
+	checkFn := c.ReadinessChecks[gvk.GroupKind()]
+	if checkFn == nil {
+		return &UnknownTypeError{GK: gvk.GroupKind()}
+	}
+
+	return w.WaitForObjects(
+		ctx, gvk, namespace, selector,
+		fmt.Sprintf("to have at least %d ready objects", minCount),
+		func(objs []unstructured.Unstructured) (done bool, err error) {
+			if len(objs) < minCount {
+				return false, nil
+			}
+			for i := range objs {
+				if done, err := checkFn(ctx, &objs[i], w.client, w.scheme); err != nil {
+					return false, fmt.Errorf("%s: %w", client.ObjectKeyFromObject(&objs[i]), err)
+				} else if !done {
+					return false, nil
+				}
+			}
+			return true, nil
+		}, opts...)
+}
+
+// Waits for all objects of the given kind matching the label selector to be gone.
+// An empty namespace selects objects in all namespaces.
+func (w *Waiter) WaitForObjectsToBeGone(
+	ctx context.Context, gvk schema.GroupVersionKind,
+	namespace string, selector labels.Selector,
+	opts ...WaitOption,
+) error {
+	return w.WaitForObjects(
+		ctx, gvk, namespace, selector, "to be gone",
+		func(objs []unstructured.Unstructured) (done bool, err error) {
+			return len(objs) == 0, nil
+		}, opts...)
+}
+
+// Waits for the objects of the given kind matching the label selector to match a check function.
+// The check function is called with all matching objects, sorted by namespace and name.
+// An empty namespace selects objects in all namespaces.
+func (w *Waiter) WaitForObjects(
+	ctx context.Context, gvk schema.GroupVersionKind,
+	namespace string, selector labels.Selector, waitReason string,
+	checkFn func(objs []unstructured.Unstructured) (done bool, err error),
+	opts ...WaitOption,
+) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	c := w.config
+	for _, opt := range opts {
+		opt.ApplyToWaiterConfig(&c)
+	}
+
+	if err := c.validateStabilityWindow(); err != nil {
+		return err
+	}
+
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	waitReason = fmt.Sprintf("matching %q %s", selector, waitReason)
+	log.Info(fmt.Sprintf("waiting %s on %s in namespace %q %s...",
+		c.Timeout, gvk, namespace, waitReason))
+
+	var observed bool
+	stability := stabilityTracker{window: c.StabilityWindow}
+	observingCheckFn := func(objs []unstructured.Unstructured) (done bool, err error) {
+		observed = observed || len(objs) > 0
+		done, err = checkFn(objs)
+		return stability.observe(done), err
+	}
+
+	err := w.waitForObjectListUntilTimeout(
+		ctx, c, gvk, namespace, selector, observingCheckFn)
+	if goerrors.Is(err, context.DeadlineExceeded) {
+		return &TimeoutError{
+			GVK:      gvk,
+			Key:      client.ObjectKey{Namespace: namespace},
+			Reason:   waitReason,
+			Timeout:  c.Timeout,
+			Observed: observed,
+			err:      err,
+		}
+	}
+	return err
+}
+
+func (w *Waiter) waitForObjectListUntilTimeout(
+	ctx context.Context, c WaiterConfig, gvk schema.GroupVersionKind,
+	namespace string, selector labels.Selector,
+	checkFn func(objs []unstructured.Unstructured) (done bool, err error),
+) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	listOpts := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}
+	newList := func() *unstructured.UnstructuredList {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		return list
+	}
+
+	if wc, ok := w.client.(client.WithWatch); ok && c.Watch {
+		err := watchObjectList(ctx, c, wc, newList, selector, listOpts, checkFn)
+		if !goerrors.Is(err, errWatchNotPossible) {
+			return err
+		}
+		logr.FromContextOrDiscard(ctx).Info(fmt.Sprintf(
+			"falling back to polling %s: %v", gvk, err))
+	}
+
+	return c.delayFunc().Until(ctx, true, false,
+		func(ctx context.Context) (done bool, err error) {
+			list := newList()
+			err = w.client.List(ctx, list, listOpts...)
+			if isPermanentError(err) {
+				return false, fmt.Errorf("listing %s: %w", gvk, err)
+			}
+			if err != nil {
+				//nolint:nilerr // retry on transient errors
+				return false, nil
+			}
+
+			return checkFn(list.Items)
+		},
+	)
+}
+
+// Waits for the check function to be done by watching the matching objects for changes.
+// The object states are re-synced every time the watch is closed by the API server.
+// With a stability window configured, the check is also re-evaluated every Interval.
+func watchObjectList(
+	ctx context.Context, c WaiterConfig, wc client.WithWatch,
+	newList func() *unstructured.UnstructuredList,
+	selector labels.Selector, listOpts []client.ListOption,
+	checkFn func(objs []unstructured.Unstructured) (done bool, err error),
+) error {
+	delay := c.delayFunc()
+	var recheck <-chan time.Time
+	if c.StabilityWindow > 0 {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		recheck = ticker.C
+	}
+
+	for {
+		// Sync the current state, the watch will only report changes after it.
+		list := newList()
+		err := wc.List(ctx, list, listOpts...)
+		if isPermanentError(err) {
+			return fmt.Errorf("listing %s: %w", list.GroupVersionKind(), err)
+		}
+		if err != nil {
+			// retry on transient errors
+			if err := sleepContext(ctx, delay()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		objs := map[client.ObjectKey]unstructured.Unstructured{}
+		for _, obj := range list.Items {
+			objs[client.ObjectKeyFromObject(&obj)] = obj
+		}
+		if done, err := checkFn(sortedObjects(objs)); err != nil || done {
+			return err
+		}
+
+		watcher, err := wc.Watch(ctx, newList(), append(listOpts, &client.ListOptions{
+			Raw: &metav1.ListOptions{ResourceVersion: list.GetResourceVersion()},
+		})...)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", errWatchNotPossible, err)
+		}
+
+		done, err := consumeObjectListWatch(
+			ctx, delay, recheck, watcher, selector, objs, checkFn)
+		watcher.Stop()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Applies watch events to objs until the check function is done or the watch is closed.
+func consumeObjectListWatch(
+	ctx context.Context, delay wait.DelayFunc, recheck <-chan time.Time,
+	watcher watch.Interface, selector labels.Selector,
+	objs map[client.ObjectKey]unstructured.Unstructured,
+	checkFn func(objs []unstructured.Unstructured) (done bool, err error),
+) (done bool, err error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+
+		case <-recheck:
+			done, err := checkFn(sortedObjects(objs))
+			if err != nil || done {
+				return done, err
+			}
+
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				// watch closed, resync.
+				return false, nil
+			}
+
+			switch event.Type {
+			case watch.Bookmark:
+				continue
+			case watch.Error:
+				// e.g. resourceVersion too old, resync after a short break.
+				return false, sleepContext(ctx, delay())
+			}
+
+			eventObj, err := eventToUnstructured(event)
+			if err != nil {
+				return false, err
+			}
+			key := client.ObjectKeyFromObject(eventObj)
+			if event.Type == watch.Deleted ||
+				!selector.Matches(labels.Set(eventObj.GetLabels())) {
+				delete(objs, key)
+			} else {
+				objs[key] = *eventObj
+			}
+
+			done, err := checkFn(sortedObjects(objs))
+			if err != nil || done {
+				return done, err
+			}
+		}
+	}
+}
+
+// Returns the objects sorted by namespace and name.
+func sortedObjects(objs map[client.ObjectKey]unstructured.Unstructured) []unstructured.Unstructured {
+	keys := make([]client.ObjectKey, 0, len(objs))
+	for key := range objs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	out := make([]unstructured.Unstructured, 0, len(objs))
+	for _, key := range keys {
+		out = append(out, objs[key])
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package dev
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestPod(name, app string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "test",
+			Labels: map[string]string{"app": app},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: status},
+			},
+		},
+	}
+}
+
+func TestWaiter_WaitForObjectsReadiness(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	podGVK := corev1.SchemeGroupVersion.WithKind("Pod")
+	selector := labels.SelectorFromSet(labels.Set{"app": "foo"})
+
+	for _, watchEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("watch=%t", watchEnabled), func(t *testing.T) {
+			ctx := context.Background()
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newTestPod("foo-1", "foo", true),
+				// not matching
+				newTestPod("bar-1", "bar", false),
+			).Build()
+			w := NewWaiter(c, scheme,
+				WithWatch(watchEnabled),
+				WithInterval(10*time.Millisecond),
+				WithTimeout(5*time.Second))
+
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				assert.NoError(t, c.Create(ctx, newTestPod("foo-2", "foo", true)))
+			}()
+
+			require.NoError(t, w.WaitForObjectsReadiness(
+				ctx, podGVK, "test", selector, 2))
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			newTestPod("foo-1", "foo", true),
+			newTestPod("foo-2", "foo", false),
+		).Build()
+		w := NewWaiter(c, scheme,
+			WithInterval(10*time.Millisecond),
+			WithTimeout(50*time.Millisecond))
+
+		err := w.WaitForObjectsReadiness(
+			context.Background(), podGVK, "test", selector, 2)
+		var timeoutErr *TimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		assert.True(t, timeoutErr.Observed)
+		assert.Contains(t, err.Error(), "app=foo")
+	})
+}
+
+func TestWaiter_WaitForObjectsToBeGone(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	podGVK := corev1.SchemeGroupVersion.WithKind("Pod")
+	selector := labels.SelectorFromSet(labels.Set{"app": "foo"})
+
+	for _, watchEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("watch=%t", watchEnabled), func(t *testing.T) {
+			ctx := context.Background()
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newTestPod("foo-1", "foo", true),
+				newTestPod("foo-2", "foo", true),
+				// not matching
+				newTestPod("bar-1", "bar", true),
+			).Build()
+			w := NewWaiter(c, scheme,
+				WithWatch(watchEnabled),
+				WithInterval(10*time.Millisecond),
+				WithTimeout(5*time.Second))
+
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				assert.NoError(t, c.Delete(ctx, newTestPod("foo-1", "foo", true)))
+				assert.NoError(t, c.Delete(ctx, newTestPod("foo-2", "foo", true)))
+			}()
+
+			require.NoError(t, w.WaitForObjectsToBeGone(ctx, podGVK, "test", selector))
+		})
+	}
+}
+
+func TestWaiter_WaitForObjectsReadiness_StabilityWindow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	podGVK := corev1.SchemeGroupVersion.WithKind("Pod")
+	selector := labels.SelectorFromSet(labels.Set{"app": "foo"})
+	window := 200 * time.Millisecond
+
+	for _, watchEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("watch=%t", watchEnabled), func(t *testing.T) {
+			ctx := context.Background()
+			pod := newTestPod("foo-1", "foo", true)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+			w := NewWaiter(c, scheme,
+				WithWatch(watchEnabled),
+				WithStabilityWindow(window),
+				WithInterval(10*time.Millisecond),
+				WithTimeout(5*time.Second))
+
+			// Flap the pod once, which has to restart the window.
+			recoveredCh := make(chan time.Time, 1)
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				flapped := newTestPod("foo-1", "foo", false)
+				flapped.SetResourceVersion(pod.GetResourceVersion())
+				assert.NoError(t, c.Status().Update(ctx, flapped))
+
+				time.Sleep(50 * time.Millisecond)
+				recoveredCh <- time.Now()
+				ready := newTestPod("foo-1", "foo", true)
+				ready.SetResourceVersion(flapped.GetResourceVersion())
+				assert.NoError(t, c.Status().Update(ctx, ready))
+			}()
+
+			require.NoError(t, w.WaitForObjectsReadiness(
+				ctx, podGVK, "test", selector, 1))
+			select {
+			case recovered := <-recoveredCh:
+				assert.GreaterOrEqual(t, time.Since(recovered), window)
+			default:
+				t.Fatal("wait succeeded before the pod recovered")
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package dev
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -8,10 +10,16 @@ import (
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestWaiterConfig_Default(t *testing.T) {
@@ -146,3 +154,321 @@ func Test_checkObjectCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestWaiter_WaitForObject_Watch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	ctx := context.Background()
+
+	obj := newTestCR("")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	w := NewWaiter(c, scheme,
+		WithWatch(true),
+		// ensure that polling would not pick up the change in time.
+		WithInterval(time.Hour),
+		WithTimeout(5*time.Second))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ripe := newTestCR("Ripe")
+		ripe.SetResourceVersion(obj.GetResourceVersion())
+		assert.NoError(t, c.Update(ctx, ripe))
+	}()
+
+	require.NoError(t, w.WaitForReadiness(ctx, newTestCR(""),
+		WithReadinessChecks{testCRGK: phaseReadinessCheck}))
+}
+
+func TestWaiter_WaitToBeGone_Watch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	ctx := context.Background()
+
+	obj := newTestCR("")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	w := NewWaiter(c, scheme,
+		WithWatch(true),
+		WithInterval(time.Hour),
+		WithTimeout(5*time.Second))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, c.Delete(ctx, newTestCR("")))
+	}()
+
+	require.NoError(t, w.WaitToBeGone(ctx, newTestCR(""),
+		func(client.Object) (bool, error) { return false, nil }))
+}
+
+func TestWaiter_WaitForObject_WatchFallback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	ctx := context.Background()
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(newTestCR("Ripe")).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Watch: func(
+				context.Context, client.WithWatch,
+				client.ObjectList, ...client.ListOption,
+			) (watch.Interface, error) {
+				return nil, errors.NewMethodNotSupported(
+					schema.GroupResource{}, "watch")
+			},
+		}).Build()
+	w := NewWaiter(c, scheme,
+		WithWatch(true),
+		WithInterval(10*time.Millisecond),
+		WithTimeout(5*time.Second))
+
+	// The first check happens while syncing before the watch is started,
+	// all following checks must come from polling.
+	var calls int
+	require.NoError(t, w.WaitForObject(ctx, newTestCR(""), "to be polled",
+		func(client.Object) (bool, error) {
+			calls++
+			return calls > 2, nil
+		}))
+}
+
+func TestWaiter_WaitForAllReadiness(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, appsv1.AddToScheme(scheme))
+
+	newDeployment := func(name string, available bool) *appsv1.Deployment {
+		status := corev1.ConditionFalse
+		if available {
+			status = corev1.ConditionTrue
+		}
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Status: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentAvailable, Status: status},
+				},
+			},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newDeployment("ok", true),
+		newDeployment("broken-1", false),
+		newDeployment("broken-2", false),
+		newTestCR(""),
+	).Build()
+	w := NewWaiter(c, scheme,
+		WithMaxConcurrency(2),
+		WithInterval(10*time.Millisecond),
+		WithTimeout(100*time.Millisecond))
+
+	err := w.WaitForAllReadiness(context.Background(), []client.Object{
+		newDeployment("ok", false),
+		newDeployment("broken-1", false),
+		newDeployment("broken-2", false),
+		// unknown types are skipped
+		newTestCR(""),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test/broken-1")
+	assert.Contains(t, err.Error(), "test/broken-2")
+	assert.NotContains(t, err.Error(), "test/ok")
+
+	var timeoutErr *TimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+}
+
+func TestWaiter_WaitForAllReadiness_MaxConcurrencyDefault(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, appsv1.AddToScheme(scheme))
+
+	newDeployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Status: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+				},
+			},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newDeployment("a"), newDeployment("b"),
+	).Build()
+	w := NewWaiter(c, scheme,
+		WithInterval(10*time.Millisecond),
+		WithTimeout(time.Second))
+
+	for _, n := range []int{0, -1} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			// must neither deadlock nor panic.
+			err := w.WaitForAllReadiness(context.Background(),
+				[]client.Object{newDeployment("a"), newDeployment("b")},
+				WithMaxConcurrency(n))
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWaiter_WaitForObject_PermanentError(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	for _, watchEnabled := range []bool{false, true} {
+		var calls int
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(
+					context.Context, client.WithWatch, client.ObjectKey,
+					client.Object, ...client.GetOption,
+				) error {
+					calls++
+					return errors.NewForbidden(
+						schema.GroupResource{Group: testCRGK.Group, Resource: "cheeses"},
+						"gouda", nil)
+				},
+			}).Build()
+		w := NewWaiter(c, scheme,
+			WithWatch(watchEnabled),
+			WithInterval(10*time.Millisecond),
+			WithTimeout(5*time.Second))
+
+		err := w.WaitForObject(context.Background(), newTestCR(""), "to exist",
+			func(client.Object) (bool, error) { return true, nil })
+		require.True(t, errors.IsForbidden(err), "watch=%t: %v", watchEnabled, err)
+		assert.Equal(t, 1, calls, "watch=%t", watchEnabled)
+	}
+}
+
+func TestWaiterConfig_delayFunc(t *testing.T) {
+	t.Run("fixed interval", func(t *testing.T) {
+		c := WaiterConfig{Interval: time.Second}
+		delay := c.delayFunc()
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, time.Second, delay())
+		}
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		c := WaiterConfig{Interval: time.Second}
+		WithBackoff{
+			Duration: 100 * time.Millisecond,
+			Factor:   2,
+			Cap:      time.Second,
+		}.ApplyToWaiterConfig(&c)
+
+		delay := c.delayFunc()
+		var delays []time.Duration
+		for i := 0; i < 6; i++ {
+			delays = append(delays, delay())
+		}
+		assert.Equal(t, []time.Duration{
+			100 * time.Millisecond,
+			200 * time.Millisecond,
+			400 * time.Millisecond,
+			800 * time.Millisecond,
+			time.Second,
+			time.Second,
+		}, delays)
+	})
+}
+
+func TestWaiter_WaitForObject_StabilityWindow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	window := 100 * time.Millisecond
+
+	for _, watchEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("watch=%t", watchEnabled), func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(newTestCR("Ripe")).Build()
+			w := NewWaiter(c, scheme,
+				WithWatch(watchEnabled),
+				WithStabilityWindow(window),
+				WithInterval(10*time.Millisecond),
+				WithTimeout(5*time.Second))
+
+			// The check regresses once, which has to restart the window.
+			var (
+				calls     int
+				regressed time.Time
+			)
+			require.NoError(t, w.WaitForObject(context.Background(), newTestCR(""), "to be stable",
+				func(client.Object) (bool, error) {
+					calls++
+					if calls == 3 {
+						regressed = time.Now()
+						return false, nil
+					}
+					return true, nil
+				}))
+			require.False(t, regressed.IsZero())
+			assert.GreaterOrEqual(t, time.Since(regressed), window)
+		})
+	}
+}
+
+func TestWaiter_WaitForObject_StabilityWindowTimeout(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(newTestCR("Ripe")).Build()
+	w := NewWaiter(c, scheme,
+		WithStabilityWindow(30*time.Millisecond),
+		WithInterval(10*time.Millisecond),
+		WithTimeout(50*time.Millisecond))
+
+	// The check keeps regressing, so the window never completes.
+	var calls int
+	err := w.WaitForObject(context.Background(), newTestCR(""), "to be stable",
+		func(client.Object) (bool, error) {
+			calls++
+			return calls%2 == 0, nil
+		})
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+
+	// A window not shorter than the timeout can never complete.
+	err = w.WaitForObject(context.Background(), newTestCR(""), "to be stable",
+		func(client.Object) (bool, error) { return true, nil },
+		WithStabilityWindow(time.Hour))
+	require.EqualError(t, err, "stability window 1h0m0s must be shorter than timeout 50ms")
+
+	err = w.WaitForObjects(context.Background(), newTestCR("").GroupVersionKind(), "", nil, "to be stable",
+		func([]unstructured.Unstructured) (bool, error) { return true, nil },
+		WithStabilityWindow(50*time.Millisecond))
+	require.ErrorContains(t, err, "must be shorter than timeout")
+}
+
+func Test_stabilityTracker(t *testing.T) {
+	t.Run("no window", func(t *testing.T) {
+		s := stabilityTracker{}
+		assert.True(t, s.observe(true))
+		assert.False(t, s.observe(false))
+	})
+
+	t.Run("regression resets", func(t *testing.T) {
+		s := stabilityTracker{window: time.Hour}
+		assert.False(t, s.observe(true))
+		assert.False(t, s.doneSince.IsZero())
+
+		assert.False(t, s.observe(false))
+		assert.True(t, s.doneSince.IsZero())
+
+		s.doneSince = time.Now().Add(-2 * time.Hour)
+		assert.True(t, s.observe(true))
+	})
+}
+
+func TestWaiter_WaitForKindDiscoverable(t *testing.T) {
+	scheme := runtime.NewScheme()
+	gvk := testCRGK.WithVersion("v1")
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).Build()
+	w := NewWaiter(c, scheme,
+		WithInterval(10*time.Millisecond),
+		WithTimeout(50*time.Millisecond))
+	ctx := context.Background()
+
+	err := w.WaitForKindDiscoverable(ctx, gvk)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "no matches for kind")
+
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+	require.NoError(t, w.WaitForKindDiscoverable(ctx, gvk))
+}
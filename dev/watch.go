@@ -0,0 +1,191 @@
+package dev
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// errWatchNotPossible is returned when an object can't be watched
+// and waiting has to fall back to polling.
+var errWatchNotPossible = goerrors.New("watch not possible")
+
+// Waits for the condition to be done by watching the object for changes.
+// The object state is re-synced every time the watch is closed by the API server.
+// With a stability window configured, the condition is also re-evaluated every Interval,
+// because it may become done without the object changing.
+func (w *Waiter) watchObjectState(
+	ctx context.Context, c WaiterConfig, wc client.WithWatch,
+	object client.Object, gvk schema.GroupVersionKind,
+	condition objectStateCondition,
+) error {
+	key := client.ObjectKeyFromObject(object)
+	delay := c.delayFunc()
+	var recheck <-chan time.Time
+	if c.StabilityWindow > 0 {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		recheck = ticker.C
+	}
+
+	for {
+		// Sync the current state, the watch will only report changes after it.
+		var exists bool
+		err := wc.Get(ctx, key, object)
+		switch {
+		case errors.IsNotFound(err):
+			if done, err := condition(object, false); err != nil || done {
+				return err
+			}
+
+		case isPermanentError(err):
+			return fmt.Errorf("getting %s %s: %w", gvk, key, err)
+
+		case err != nil:
+			// retry on transient errors
+			if err := sleepContext(ctx, delay()); err != nil {
+				return err
+			}
+			continue
+
+		default:
+			if done, err := condition(object, true); err != nil || done {
+				return err
+			}
+			exists = true
+		}
+
+		done, err := w.watchObjectStateFrom(
+			ctx, delay, recheck, wc, object, gvk, exists, condition)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Watches the object starting at the last observed state,
+// until the condition is done or the watch is closed.
+func (w *Waiter) watchObjectStateFrom(
+	ctx context.Context, delay wait.DelayFunc, recheck <-chan time.Time,
+	wc client.WithWatch, object client.Object, gvk schema.GroupVersionKind,
+	exists bool, condition objectStateCondition,
+) (done bool, err error) {
+	key := client.ObjectKeyFromObject(object)
+	var resourceVersion string
+	if exists {
+		resourceVersion = object.GetResourceVersion()
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	watcher, err := wc.Watch(ctx, list,
+		client.InNamespace(key.Namespace),
+		client.MatchingFields{"metadata.name": key.Name},
+		&client.ListOptions{Raw: &metav1.ListOptions{
+			ResourceVersion: resourceVersion,
+		}},
+	)
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errWatchNotPossible, err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+
+		case <-recheck:
+			done, err := condition(object, exists)
+			if err != nil || done {
+				return done, err
+			}
+
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				// watch closed, resync.
+				return false, nil
+			}
+
+			switch event.Type {
+			case watch.Bookmark:
+				continue
+			case watch.Error:
+				// e.g. resourceVersion too old, resync after a short break.
+				return false, sleepContext(ctx, delay())
+			}
+
+			eventObj, err := eventToUnstructured(event)
+			if err != nil {
+				return false, err
+			}
+			if eventObj.GetName() != key.Name ||
+				eventObj.GetNamespace() != key.Namespace {
+				continue
+			}
+			if err := copyFromUnstructured(eventObj, object); err != nil {
+				return false, err
+			}
+
+			exists = event.Type != watch.Deleted
+			done, err := condition(object, exists)
+			if err != nil || done {
+				return done, err
+			}
+		}
+	}
+}
+
+// Returns the object of a watch event as unstructured.
+// Depending on the client, watches may return typed objects even when requested as unstructured.
+func eventToUnstructured(event watch.Event) (*unstructured.Unstructured, error) {
+	obj, ok := event.Object.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T in watch event", event.Object)
+	}
+
+	unstrObj := &unstructured.Unstructured{}
+	if err := copyToUnstructured(obj, unstrObj); err != nil {
+		return nil, err
+	}
+	return unstrObj, nil
+}
+
+// Copies the state of the unstructured src object into dst.
+func copyFromUnstructured(src *unstructured.Unstructured, dst client.Object) error {
+	if unstrDst, ok := dst.(*unstructured.Unstructured); ok {
+		unstrDst.Object = src.DeepCopy().Object
+		return nil
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		src.Object, dst); err != nil {
+		return fmt.Errorf("can't convert from unstructured: %w", err)
+	}
+	return nil
+}
+
+// Sleeps for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}